@@ -0,0 +1,160 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/eiso/gpass/encrypt"
+)
+
+// socketName is the name of the agent's socket in $XDG_RUNTIME_DIR
+const socketName = "gpass-agent.sock"
+
+// requestTimeout is how long a client has to send its request and read the response
+const requestTimeout = 10 * time.Second
+
+// request is sent by a client, one per connection
+type request struct {
+	// Op is either "decrypt" or "lock"
+	Op         string `json:"op"`
+	Message    []byte `json:"message,omitempty"`
+	Recipients []byte `json:"recipients,omitempty"`
+	Insecure   bool   `json:"insecure,omitempty"`
+}
+
+// response answers a request
+type response struct {
+	Message []byte `json:"message,omitempty"`
+	// Author is the key id of the trusted signer of the message, 0 if it was not verified
+	Author uint64 `json:"author,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// SocketPath returns the path of the agent's socket
+func SocketPath() (string, error) {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		return "", fmt.Errorf("XDG_RUNTIME_DIR is not set")
+	}
+
+	return path.Join(dir, socketName), nil
+}
+
+// Serve answers the decrypt requests of other gpass processes with the unlocked private key of p until
+// it is locked or no request has been made for the idle timeout, the private key is wiped on return.
+// Every connection is handled on its own so a client that does not send its request can not block others.
+func Serve(p *encrypt.PGP, timeout time.Duration) error {
+	defer p.Lock()
+
+	// the key is only wiped once the requests being handled are answered
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	socket, err := SocketPath()
+	if err != nil {
+		return err
+	}
+
+	if Running() {
+		return fmt.Errorf("An agent is already listening on %s", socket)
+	}
+
+	// a socket left behind by an agent that did not exit cleanly
+	os.Remove(socket)
+
+	// the socket is created with 0600 permissions so only the user can connect
+	mask := syscall.Umask(0177)
+	l, err := net.Listen("unix", socket)
+	syscall.Umask(mask)
+	if err != nil {
+		return fmt.Errorf("Unable to listen on %s: %s", socket, err)
+	}
+
+	var once sync.Once
+	stop := func() {
+		once.Do(func() { l.Close() })
+	}
+	defer stop()
+
+	idle := time.AfterFunc(timeout, stop)
+	defer idle.Stop()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			// the listener is closed by a lock request or the idle timeout
+			return nil
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			// only decrypt requests keep the agent alive, not clients checking if it is running
+			switch handle(conn) {
+			case "lock":
+				stop()
+			case "decrypt":
+				idle.Reset(timeout)
+			}
+		}()
+	}
+}
+
+// decrypting serializes the decryption of requests, which share the keys loaded by encrypt
+var decrypting sync.Mutex
+
+// handle answers a single request and returns its operation, empty if the request was invalid
+func handle(conn net.Conn) string {
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(requestTimeout))
+
+	var req request
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		json.NewEncoder(conn).Encode(&response{Error: fmt.Sprintf("Invalid request: %s", err)})
+		return ""
+	}
+
+	switch req.Op {
+	case "lock":
+		json.NewEncoder(conn).Encode(&response{})
+	case "decrypt":
+		decrypting.Lock()
+		res := decrypt(&req)
+		decrypting.Unlock()
+		json.NewEncoder(conn).Encode(res)
+	default:
+		json.NewEncoder(conn).Encode(&response{Error: fmt.Sprintf("Unknown operation: %s", req.Op)})
+	}
+
+	return req.Op
+}
+
+func decrypt(req *request) *response {
+	rc, err := encrypt.NewRecipients(req.Recipients)
+	if err != nil {
+		return &response{Error: err.Error()}
+	}
+
+	p := encrypt.NewPGP(nil, req.Message, true)
+	p.Recipients = rc
+	p.Insecure = req.Insecure
+
+	if err := p.Decrypt(); err != nil {
+		return &response{Error: err.Error()}
+	}
+
+	res := &response{Message: p.Message}
+	if p.Author != nil {
+		res.Author = p.Author.PrimaryKey.KeyId
+	}
+
+	return res
+}
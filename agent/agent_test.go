@@ -0,0 +1,138 @@
+package agent
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/eiso/gpass/encrypt"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gpass-agent")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	os.Setenv("XDG_RUNTIME_DIR", dir)
+
+	k, err := encrypt.GenerateKey("John Doe", "john@doe.org", []byte("passphrase"))
+	require.NoError(t, err)
+
+	p := encrypt.NewPGP(k, []byte("hunter2"), false)
+	require.NoError(t, p.LoadKeys())
+	require.NoError(t, p.Unlock([]byte("passphrase")))
+	require.NoError(t, p.Encrypt())
+	message := p.Message
+
+	done := make(chan error)
+	go func() {
+		done <- Serve(p, time.Minute)
+	}()
+
+	var c *Client
+	for i := 0; i < 100; i++ {
+		if c, err = Dial(); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.NoError(t, err)
+
+	socket, err := SocketPath()
+	require.NoError(t, err)
+	fi, err := os.Stat(socket)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+
+	m, author, err := c.Decrypt(message, nil, false)
+	require.NoError(t, err)
+	require.Equal(t, "hunter2", string(m))
+	require.NotZero(t, author)
+
+	_, _, err = c.Decrypt([]byte("not a message"), nil, false)
+	require.Error(t, err)
+
+	require.NoError(t, c.Lock())
+	require.NoError(t, <-done)
+	require.False(t, Running())
+
+	// the key is wiped once the agent has stopped
+	_, err = p.Signer()
+	require.Error(t, err)
+}
+
+func TestAgentTimeout(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gpass-agent")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	os.Setenv("XDG_RUNTIME_DIR", dir)
+
+	done := make(chan error)
+	go func() {
+		done <- Serve(encrypt.NewPGP(nil, nil, true), 100*time.Millisecond)
+	}()
+
+	// checking if the agent is running does not keep it alive
+	deadline := time.After(5 * time.Second)
+	for stopped := false; !stopped; {
+		select {
+		case err := <-done:
+			require.NoError(t, err)
+			stopped = true
+		case <-deadline:
+			t.Fatal("the agent did not stop after the idle timeout")
+		case <-time.After(20 * time.Millisecond):
+			Running()
+		}
+	}
+
+	require.False(t, Running())
+}
+
+func TestAgentIdleClient(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gpass-agent")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	os.Setenv("XDG_RUNTIME_DIR", dir)
+
+	done := make(chan error)
+	go func() {
+		done <- Serve(encrypt.NewPGP(nil, nil, true), time.Minute)
+	}()
+
+	var c *Client
+	for i := 0; i < 100; i++ {
+		if c, err = Dial(); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.NoError(t, err)
+
+	// a client that connects without sending a request does not block others
+	socket, err := SocketPath()
+	require.NoError(t, err)
+	idle, err := net.Dial("unix", socket)
+	require.NoError(t, err)
+
+	locked := make(chan error)
+	go func() {
+		locked <- c.Lock()
+	}()
+
+	select {
+	case err := <-locked:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the lock request was blocked by an idle client")
+	}
+
+	idle.Close()
+	require.NoError(t, <-done)
+	require.False(t, Running())
+}
@@ -0,0 +1,78 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+)
+
+// Client sends requests to a running agent, it implements encrypt.Agent
+type Client struct {
+	socket string
+}
+
+// Dial returns a client for the running agent or an error if no agent is running
+func Dial() (*Client, error) {
+	socket, err := SocketPath()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, fmt.Errorf("No agent is running: %s", err)
+	}
+	conn.Close()
+
+	return &Client{socket: socket}, nil
+}
+
+// Running returns true if an agent is listening on the socket
+func Running() bool {
+	_, err := Dial()
+	return err == nil
+}
+
+// Decrypt asks the agent to decrypt a message and verify its signer against the armored recipients
+func (c *Client) Decrypt(message []byte, recipients []byte, insecure bool) ([]byte, uint64, error) {
+	res, err := c.send(&request{
+		Op:         "decrypt",
+		Message:    message,
+		Recipients: recipients,
+		Insecure:   insecure,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return res.Message, res.Author, nil
+}
+
+// Lock makes the agent wipe the private key and exit
+func (c *Client) Lock() error {
+	_, err := c.send(&request{Op: "lock"})
+	return err
+}
+
+func (c *Client) send(req *request) (*response, error) {
+	conn, err := net.Dial("unix", c.socket)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to connect to the agent: %s", err)
+	}
+	defer conn.Close()
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, fmt.Errorf("Unable to send the request to the agent: %s", err)
+	}
+
+	var res response
+	if err := json.NewDecoder(conn).Decode(&res); err != nil {
+		return nil, fmt.Errorf("Unable to read the response of the agent: %s", err)
+	}
+
+	if res.Error != "" {
+		return nil, fmt.Errorf("%s", res.Error)
+	}
+
+	return &res, nil
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/eiso/gpass/agent"
+	"github.com/eiso/gpass/encrypt"
+	"github.com/eiso/gpass/utils"
+	"github.com/spf13/cobra"
+)
+
+type AgentCmd struct {
+	timeout time.Duration
+}
+
+func NewAgentCmd() *AgentCmd {
+	return &AgentCmd{}
+}
+
+func (c *AgentCmd) Cmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "agent",
+		Args:  cobra.NoArgs,
+		Short: "Keeps the unlocked private key in memory to decrypt accounts without asking for the passphrase.",
+		RunE:  c.Execute,
+	}
+
+	cmd.Flags().DurationVarP(&c.timeout, "timeout", "t", 15*time.Minute, "Wipe the key and exit after being idle this long.")
+
+	return cmd
+}
+
+func (c *AgentCmd) Execute(cmd *cobra.Command, args []string) error {
+	if err := InitCheck(); err != nil {
+		return err
+	}
+
+	if c.timeout <= 0 {
+		return fmt.Errorf("the timeout must be positive")
+	}
+
+	socket, err := agent.SocketPath()
+	if err != nil {
+		return err
+	}
+
+	if agent.Running() {
+		return fmt.Errorf("an agent is already running, stop it with: gpass lock")
+	}
+
+	p, err := unlockKey(nil, true)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Listening on %s, the key is wiped after %s without requests or on: gpass lock\n", socket, c.timeout)
+
+	if err := agent.Serve(p, c.timeout); err != nil {
+		return err
+	}
+
+	fmt.Println("The key has been wiped")
+
+	return nil
+}
+
+// decryptKey returns a PGP that decrypts through the agent when one is running, or else with the
+// private key unlocked by the passphrase
+func decryptKey(m []byte) (*encrypt.PGP, error) {
+	a, err := agent.Dial()
+	if err != nil {
+		return unlockKey(m, true)
+	}
+
+	pk, err := utils.LoadFile(Cfg.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	p := encrypt.NewPGP(pk, m, true)
+
+	if err := p.LoadKeys(); err != nil {
+		return nil, err
+	}
+
+	p.Agent = a
+
+	return p, nil
+}
@@ -2,11 +2,7 @@ package cmd
 
 import (
 	"fmt"
-	"os"
-	"path"
-	"strings"
 
-	"github.com/eiso/gpass/utils"
 	"github.com/spf13/cobra"
 )
 
@@ -38,17 +34,13 @@ func (c *CpCmd) Execute(cmd *cobra.Command, args []string) error {
 	r := Cfg.Repository
 	filename := args[0] + ".gpg"
 	new := args[1] + ".gpg"
-	pathPrev := path.Join(r.Path, filename)
-	pathNew := path.Join(r.Path, new)
-	d := strings.Split(filename, string(os.PathSeparator))
-	root := path.Join(r.Path, d[0])
 
 	if err := r.Load(); err != nil {
 		return err
 	}
 
 	if !r.BranchExists("gpass") {
-		return fmt.Errorf("gpass has not been initialized yet, please run: gpass init")
+		return notInitialized(r)
 	}
 
 	if !r.BranchExists(filename) {
@@ -59,23 +51,32 @@ func (c *CpCmd) Execute(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("%s already exists", args[1])
 	}
 
-	if err := r.CreateBranch(filename, new); err != nil {
+	p, err := unlockKey(nil, true)
+	if err != nil {
 		return err
 	}
 
-	if err := utils.RenamePath(pathPrev, pathNew); err != nil {
+	m, err := reencryptTo(r, p, args[0], args[1])
+	if err != nil {
 		return err
 	}
 
-	if err := utils.DeletePath(root); err != nil {
+	if err := r.CreateBranch(filename, new); err != nil {
 		return err
 	}
 
 	msg := fmt.Sprintf("Copied: %s to %s", args[0], args[1])
-	if err := r.CommitFile(Cfg.User, new, msg); err != nil {
+	if err := r.MoveFile(Cfg.User, new, filename, new, msg); err != nil {
 		return err
 	}
 
+	if m != nil {
+		msg := fmt.Sprintf("Re-encrypt: %s for its new recipients", args[1])
+		if err := r.WriteFile(Cfg.User, new, new, m, msg); err != nil {
+			return err
+		}
+	}
+
 	fmt.Println("Successfully moved the account to:", args[1])
 
 	return nil
@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/eiso/gpass/encrypt"
+	"github.com/eiso/gpass/git"
+	"github.com/spf13/cobra"
+)
+
+type DiffCmd struct {
+	from     string
+	to       string
+	reveal   bool
+	insecure bool
+}
+
+func NewDiffCmd() *DiffCmd {
+	return &DiffCmd{}
+}
+
+func (c *DiffCmd) Cmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff",
+		Args:  cobra.ExactArgs(1),
+		Short: "Shows the changes between two decrypted revisions of an account.",
+		RunE:  c.Execute,
+	}
+
+	cmd.Flags().StringVar(&c.from, "from", "1", "The old revision: n commits ago, a commit hash or a date.")
+	cmd.Flags().StringVar(&c.to, "to", "0", "The new revision: n commits ago, a commit hash or a date.")
+	cmd.Flags().BoolVar(&c.reveal, "reveal", false, "Show the password line instead of masking it.")
+	cmd.Flags().BoolVar(&c.insecure, "insecure", false, "Also show revisions that are not signed by a trusted recipient.")
+
+	return cmd
+}
+
+func (c *DiffCmd) Execute(cmd *cobra.Command, args []string) error {
+	if err := InitCheck(); err != nil {
+		return err
+	}
+
+	if len(args) != 1 {
+		return fmt.Errorf("please provide the name of the account")
+	}
+
+	r := Cfg.Repository
+	filename := args[0] + ".gpg"
+
+	if err := r.Load(); err != nil {
+		return err
+	}
+
+	if !r.BranchExists("gpass") {
+		return notInitialized(r)
+	}
+
+	if !r.BranchExists(filename) && !r.TagExists(filename) {
+		return fmt.Errorf("the account does not exist")
+	}
+
+	from, err := r.Revision(filename, c.from)
+	if err != nil {
+		return err
+	}
+
+	to, err := r.Revision(filename, c.to)
+	if err != nil {
+		return err
+	}
+
+	p, err := decryptKey(nil)
+	if err != nil {
+		return err
+	}
+
+	p.Insecure = c.insecure
+
+	if err := loadRecipients(r, p, args[0]); err != nil {
+		return err
+	}
+
+	a, err := decryptRevision(r, p, from, filename)
+	if err != nil {
+		return err
+	}
+
+	b, err := decryptRevision(r, p, to, filename)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("--- %s@%s (%s)\n", args[0], from.Hash[:7], from.When.Format("2006-01-02 15:04"))
+	fmt.Printf("+++ %s@%s (%s)\n", args[0], to.Hash[:7], to.When.Format("2006-01-02 15:04"))
+
+	for _, d := range diffLines(a, b) {
+		text := d.text
+		if !c.reveal && d.password {
+			text = strings.Repeat("*", 8)
+		}
+
+		fmt.Printf("%c %s\n", d.op, text)
+	}
+
+	return nil
+}
+
+// decryptRevision returns the decrypted lines of an account at a revision, a revision
+// without the account such as its removal has no lines
+func decryptRevision(r *git.Repository, p *encrypt.PGP, rev *git.Revision, filename string) ([]string, error) {
+	f, err := r.ReadRevision(rev.Hash, filename)
+	if err != nil {
+		return nil, nil
+	}
+
+	p.Message = f
+	p.Encrypted = true
+
+	if err := p.Decrypt(); err != nil {
+		return nil, err
+	}
+
+	return parseEntry(p.Message).lines, nil
+}
+
+// diffLine is a single line of a diff
+type diffLine struct {
+	// op is ' ' for unchanged, '-' for removed and '+' for added lines
+	op   byte
+	text string
+	// password is true if the line is the first line of its revision
+	password bool
+}
+
+// diffLines returns the line diff of a and b based on their longest common subsequence
+func diffLines(a []string, b []string) []diffLine {
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var d []diffLine
+	i, j := 0, 0
+
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			d = append(d, diffLine{' ', a[i], i == 0 || j == 0})
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			d = append(d, diffLine{'-', a[i], i == 0})
+			i++
+		default:
+			d = append(d, diffLine{'+', b[j], j == 0})
+			j++
+		}
+	}
+
+	return d
+}
@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffLines(t *testing.T) {
+	a := []string{"hunter2", "username: eiso", "url: https://github.com"}
+	b := []string{"correct-horse", "username: eiso", "url: https://github.com", "otp: 123"}
+
+	require.Equal(t, []diffLine{
+		{'-', "hunter2", true},
+		{'+', "correct-horse", true},
+		{' ', "username: eiso", false},
+		{' ', "url: https://github.com", false},
+		{'+', "otp: 123", false},
+	}, diffLines(a, b))
+
+	require.Equal(t, []diffLine{
+		{'+', "hunter2", true},
+	}, diffLines(nil, a[:1]))
+
+	require.Equal(t, []diffLine{
+		{' ', "hunter2", true},
+		{'-', "username: eiso", false},
+	}, diffLines(a[:2], a[:1]))
+}
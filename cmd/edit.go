@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/eiso/gpass/utils"
+	"github.com/spf13/cobra"
+)
+
+type EditCmd struct{}
+
+func NewEditCmd() *EditCmd {
+	return &EditCmd{}
+}
+
+func (c *EditCmd) Cmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "edit",
+		Args:  cobra.ExactArgs(1),
+		Short: "Edits an encrypted account in your $EDITOR.",
+		RunE:  c.Execute,
+	}
+
+	return cmd
+}
+
+func (c *EditCmd) Execute(cmd *cobra.Command, args []string) error {
+	if err := InitCheck(); err != nil {
+		return err
+	}
+
+	if len(args) != 1 {
+		return fmt.Errorf("please provide a name for the account you are editing")
+	}
+
+	r := Cfg.Repository
+	filename := args[0] + ".gpg"
+
+	if err := r.Load(); err != nil {
+		return err
+	}
+
+	if !r.BranchExists("gpass") {
+		return notInitialized(r)
+	}
+
+	if !r.BranchExists(filename) {
+		return fmt.Errorf("the account does not exist")
+	}
+
+	f, err := r.ReadFile(filename, filename)
+	if err != nil {
+		return err
+	}
+
+	p, err := unlockKey(f, true)
+	if err != nil {
+		return err
+	}
+
+	if err := loadRecipients(r, p, args[0]); err != nil {
+		return err
+	}
+
+	if err := p.Decrypt(); err != nil {
+		return err
+	}
+
+	m, err := utils.EditorShellPrompt(p.Message)
+	if err != nil {
+		return err
+	}
+
+	if bytes.Equal(m, p.Message) {
+		fmt.Println("No changes made to", args[0])
+		return nil
+	}
+
+	p.Message = m
+
+	if err := p.Encrypt(); err != nil {
+		return err
+	}
+
+	msg := fmt.Sprintf("Edit: %s", args[0])
+	if err := r.WriteFile(Cfg.User, filename, filename, p.Message, msg); err != nil {
+		return err
+	}
+
+	fmt.Println("Successfully edited the account", args[0])
+
+	return nil
+}
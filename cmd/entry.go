@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"strings"
+)
+
+// entry is a decrypted account following the pass convention: the first line is the password
+// and the following lines are `key: value` fields or free form notes
+type entry struct {
+	lines  []string
+	fields []field
+}
+
+// field is a single `key: value` line of an entry
+type field struct {
+	key   string
+	value string
+	// line is the 1-based line number of the field in the entry
+	line int
+}
+
+// parseEntry parses a decrypted message into an entry
+func parseEntry(m []byte) *entry {
+	e := &entry{lines: strings.Split(string(m), "\n")}
+
+	for i, l := range e.lines {
+		if i == 0 {
+			continue
+		}
+
+		// a line starting with a URL such as otpauth://totp/... is not a field
+		kv := strings.SplitN(l, ":", 2)
+		if len(kv) != 2 || strings.HasPrefix(kv[1], "//") {
+			continue
+		}
+
+		k := strings.TrimSpace(kv[0])
+		if k == "" || strings.ContainsAny(k, " \t") {
+			continue
+		}
+
+		e.fields = append(e.fields, field{
+			key:   k,
+			value: strings.TrimSpace(kv[1]),
+			line:  i + 1,
+		})
+	}
+
+	return e
+}
+
+// password returns the first line of the entry
+func (e *entry) password() string {
+	return e.lines[0]
+}
+
+// setPassword replaces the first line of the entry
+func (e *entry) setPassword(p string) {
+	e.lines[0] = p
+}
+
+// field returns the value of the first field matching key, case-insensitive
+func (e *entry) field(key string) (string, bool) {
+	for _, f := range e.fields {
+		if strings.EqualFold(f.key, key) {
+			return f.value, true
+		}
+	}
+
+	return "", false
+}
+
+// line returns the n-th line of the entry, starting at 1 for the password
+func (e *entry) line(n int) (string, bool) {
+	if n < 1 || n > len(e.lines) {
+		return "", false
+	}
+
+	return e.lines[n-1], true
+}
+
+// bytes returns the entry as a message, preserving the original formatting
+func (e *entry) bytes() []byte {
+	return []byte(strings.Join(e.lines, "\n"))
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEntry(t *testing.T) {
+	m := "hunter2\nusername: eiso\nURL: https://github.com\nrecovery codes below\n  otp : otpauth://totp/x?secret=y\n"
+	e := parseEntry([]byte(m))
+
+	require.Equal(t, "hunter2", e.password())
+
+	v, ok := e.field("username")
+	require.True(t, ok)
+	require.Equal(t, "eiso", v)
+
+	v, ok = e.field("url")
+	require.True(t, ok)
+	require.Equal(t, "https://github.com", v)
+
+	v, ok = e.field("otp")
+	require.True(t, ok)
+	require.Equal(t, "otpauth://totp/x?secret=y", v)
+
+	_, ok = e.field("recovery codes below")
+	require.False(t, ok)
+
+	// a URL is not a field
+	_, ok = parseEntry([]byte("hunter2\notpauth://totp/x?secret=y\n")).field("otpauth")
+	require.False(t, ok)
+
+	l, ok := e.line(4)
+	require.True(t, ok)
+	require.Equal(t, "recovery codes below", l)
+
+	_, ok = e.line(0)
+	require.False(t, ok)
+
+	require.Equal(t, m, string(e.bytes()))
+
+	e.setPassword("correct-horse")
+	require.Equal(t, "correct-horse"+m[len("hunter2"):], string(e.bytes()))
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/eiso/gpass/generate"
+	"github.com/spf13/cobra"
+)
+
+type GenerateCmd struct {
+	noSymbols   bool
+	noAmbiguous bool
+	required    []string
+	diceware    bool
+	inPlace     bool
+}
+
+func NewGenerateCmd() *GenerateCmd {
+	return &GenerateCmd{}
+}
+
+func (c *GenerateCmd) Cmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "generate account [length]",
+		Args:  cobra.RangeArgs(1, 2),
+		Short: "Generates a new password and stores it as an encrypted account.",
+		RunE:  c.Execute,
+	}
+
+	cmd.Flags().BoolVarP(&c.noSymbols, "no-symbols", "n", false, "Do not use any symbols in the password.")
+	cmd.Flags().BoolVarP(&c.noAmbiguous, "no-ambiguous", "a", false, "Exclude ambiguous characters such as 0, O, 1 and l.")
+	cmd.Flags().StringSliceVarP(&c.required, "require", "r", nil, "Character classes that must appear at least once: lower, upper, digit, symbol.")
+	cmd.Flags().BoolVarP(&c.diceware, "diceware", "d", false, "Generate a passphrase of [length] words from the bundled wordlist.")
+	cmd.Flags().BoolVarP(&c.inPlace, "in-place", "i", false, "Replace only the first line of an existing account and keep its history.")
+
+	return cmd
+}
+
+func (c *GenerateCmd) Execute(cmd *cobra.Command, args []string) error {
+	if err := InitCheck(); err != nil {
+		return err
+	}
+
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("please provide a name for the account you are generating")
+	}
+
+	length := 24
+	if c.diceware {
+		length = 6
+	}
+
+	if len(args) == 2 {
+		l, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("the length must be a number: %s", args[1])
+		}
+		length = l
+	}
+
+	var pass string
+	var err error
+
+	if c.diceware {
+		pass, err = generate.Passphrase(length, "-")
+	} else {
+		pass, err = generate.Password(&generate.Policy{
+			Length:      length,
+			NoSymbols:   c.noSymbols,
+			NoAmbiguous: c.noAmbiguous,
+			Required:    c.required,
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	r := Cfg.Repository
+	filename := args[0] + ".gpg"
+
+	if err := r.Load(); err != nil {
+		return err
+	}
+
+	if !r.BranchExists("gpass") {
+		return notInitialized(r)
+	}
+
+	if c.inPlace {
+		if !r.BranchExists(filename) {
+			return fmt.Errorf("the account does not exist")
+		}
+
+		if err := c.replaceFirstLine(args[0], pass); err != nil {
+			return err
+		}
+	} else {
+		if r.BranchExists(filename) {
+			return fmt.Errorf("the account already exists, use --in-place to replace its password")
+		}
+
+		if err := insertAccount(r, args[0], []byte(pass)); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("The generated password for %s is:\n%s\n", args[0], pass)
+
+	return nil
+}
+
+// replaceFirstLine decrypts an existing account and replaces its password line
+func (c *GenerateCmd) replaceFirstLine(account string, pass string) error {
+	r := Cfg.Repository
+	filename := account + ".gpg"
+
+	f, err := r.ReadFile(filename, filename)
+	if err != nil {
+		return err
+	}
+
+	p, err := unlockKey(f, true)
+	if err != nil {
+		return err
+	}
+
+	if err := loadRecipients(r, p, account); err != nil {
+		return err
+	}
+
+	if err := p.Decrypt(); err != nil {
+		return err
+	}
+
+	e := parseEntry(p.Message)
+	e.setPassword(pass)
+
+	p.Message = e.bytes()
+
+	if err := p.Encrypt(); err != nil {
+		return err
+	}
+
+	msg := fmt.Sprintf("Generate: %s", account)
+	if err := r.WriteFile(Cfg.User, filename, filename, p.Message, msg); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+type GrepCmd struct {
+	ignoreCase bool
+	insecure   bool
+}
+
+func NewGrepCmd() *GrepCmd {
+	return &GrepCmd{}
+}
+
+func (c *GrepCmd) Cmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "grep regex",
+		Args:  cobra.ExactArgs(1),
+		Short: "Searches the decrypted contents of all accounts.",
+		RunE:  c.Execute,
+	}
+
+	cmd.Flags().BoolVarP(&c.ignoreCase, "ignore-case", "i", false, "Match the regex case-insensitively.")
+	cmd.Flags().BoolVar(&c.insecure, "insecure", false, "Also search accounts that are not signed by a trusted signer or recipient.")
+
+	return cmd
+}
+
+func (c *GrepCmd) Execute(cmd *cobra.Command, args []string) error {
+	if err := InitCheck(); err != nil {
+		return err
+	}
+
+	if len(args) != 1 {
+		return fmt.Errorf("please provide a regex to search for")
+	}
+
+	expr := args[0]
+	if c.ignoreCase {
+		expr = "(?i)" + expr
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return fmt.Errorf("invalid regex: %s", err)
+	}
+
+	r := Cfg.Repository
+
+	if err := r.Load(); err != nil {
+		return err
+	}
+
+	if !r.BranchExists("gpass") {
+		return notInitialized(r)
+	}
+
+	signers, err := trustedSigners(r, c.insecure)
+	if err != nil {
+		return err
+	}
+
+	p, err := decryptKey(nil)
+	if err != nil {
+		return err
+	}
+	p.Insecure = c.insecure
+
+	for _, account := range listAccounts(r) {
+		filename := account + ".gpg"
+
+		_, err := r.VerifyBranch(filename, signers)
+		if err := untrusted(filename, err, c.insecure); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			continue
+		}
+
+		f, err := r.ReadFile(filename, filename)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", account, err)
+			continue
+		}
+
+		p.Message = f
+		p.Encrypted = true
+
+		if err := loadRecipients(r, p, account); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", account, err)
+			continue
+		}
+
+		if err := p.Decrypt(); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", account, err)
+			continue
+		}
+
+		for _, l := range strings.Split(string(p.Message), "\n") {
+			if re.MatchString(l) {
+				fmt.Printf("%s: %s\n", account, l)
+			}
+		}
+	}
+
+	return nil
+}
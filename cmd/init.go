@@ -12,7 +12,14 @@ import (
 )
 
 type InitCmd struct {
-	key string
+	key         string
+	create      bool
+	bare        bool
+	generateKey bool
+	name        string
+	email       string
+	remote      string
+	insecure    bool
 }
 
 func NewInitCmd() *InitCmd {
@@ -28,7 +35,13 @@ func (c *InitCmd) Cmd() *cobra.Command {
 	}
 
 	cmd.Flags().StringVarP(&c.key, "key", "k", "", "Path to your local private key.")
-	cmd.MarkFlagRequired("key")
+	cmd.Flags().BoolVarP(&c.create, "create", "c", false, "Create a new git repository at the path.")
+	cmd.Flags().BoolVar(&c.bare, "bare", false, "Create a new bare git repository at the path, such as ~/.gpass.git.")
+	cmd.Flags().BoolVarP(&c.generateKey, "generate-key", "g", false, "Generate a new private key, written to --key or ~/.gpass/private-key.asc.")
+	cmd.Flags().StringVar(&c.name, "name", "", "Name for the generated key, defaults to your git user.name.")
+	cmd.Flags().StringVar(&c.email, "email", "", "Email for the generated key, defaults to your git user.email.")
+	cmd.Flags().StringVarP(&c.remote, "remote", "r", "", "URL of a git remote to add as origin for push, pull and sync.")
+	cmd.Flags().BoolVar(&c.insecure, "insecure", false, "Only warn about changes on the remote that are not signed by one of its signers.")
 
 	return cmd
 }
@@ -42,43 +55,99 @@ func (c *InitCmd) Execute(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	r.Path = args[0]
+	var pass []byte
 
-	if err := r.Load(); err != nil {
-		return err
-	}
-
-	if !r.BranchExists("gpass") {
-		err := r.CreateOrphanBranch(u, "gpass")
+	if c.generateKey {
+		p, err := c.generate(u)
 		if err != nil {
 			return err
 		}
+		pass = p
+	} else if c.key == "" {
+		return fmt.Errorf("please provide your private key with --key or create one with --generate-key")
 	}
 
-	e := ".empty"
-	p := path.Join(r.Path, e)
-
-	if err := utils.TouchFile(p); err != nil {
+	f, err := utils.LoadFile(c.key)
+	if err != nil {
 		return err
 	}
 
-	if err := r.CommitFile(u, e, "Initial commit."); err != nil {
+	k := encrypt.NewPGP(f, nil, true)
+
+	if err := k.LoadKeys(); err != nil {
 		return err
 	}
 
-	f, err := utils.LoadFile(c.key)
-	if err != nil {
+	// the key is unlocked before anything is committed so the gpass branch is signed too
+	if c.generateKey {
+		if err := k.Unlock(pass); err != nil {
+			return err
+		}
+	} else {
+		if err := k.Keyring(3); err != nil {
+			return fmt.Errorf("only 3 passphrase attempts allowed: %s", err)
+		}
+	}
+
+	if u.SignKey, err = k.Signer(); err != nil {
 		return err
 	}
 
-	k := encrypt.NewPGP(f, nil, true)
+	r.Path = args[0]
 
-	if err := k.LoadKeys(); err != nil {
-		return err
+	if c.bare {
+		if err := r.CreateBare(); err != nil {
+			return err
+		}
+	} else if c.create {
+		if err := r.Create(); err != nil {
+			return err
+		}
+	} else {
+		if err := r.Load(); err != nil {
+			return err
+		}
+	}
+
+	if r.Legacy() {
+		return fmt.Errorf("the store was created by an older version of gpass, please run: gpass migrate")
 	}
 
-	if err := k.Keyring(3); err != nil {
-		return fmt.Errorf("only 3 passphrase attempts allowed: %s", err)
+	// pull an existing store from the remote before creating the gpass branch
+	if c.remote != "" {
+		if err := r.AddRemote("origin", c.remote); err != nil {
+			return err
+		}
+
+		if err := r.Fetch("origin"); err != nil {
+			return err
+		}
+
+		if _, err := fastForward(r, "origin", c.insecure, "initialize from"); err != nil {
+			return err
+		}
+	}
+
+	if !r.BranchExists("gpass") {
+		err := r.CreateOrphanBranch(u, "gpass")
+		if err != nil {
+			return err
+		}
+
+		if err := r.WriteFile(u, "gpass", ".empty", []byte{}, "Initial commit."); err != nil {
+			return err
+		}
+
+		// a new store trusts the commits signed by its creator
+		pub, err := k.PublicKey()
+		if err != nil {
+			return err
+		}
+
+		msg := fmt.Sprintf("Add signers: [%s]", encrypt.KeyID(u.SignKey))
+		if err := r.WriteFile(u, "gpass", signersFile, pub, msg); err != nil {
+			return err
+		}
 	}
 
 	Cfg.User = u
@@ -89,6 +158,53 @@ func (c *InitCmd) Execute(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("Failed to save the user config: %s", err)
 	}
 
+	if r.IsBare() {
+		fmt.Println("The repository is bare, gpass only uses its object store")
+	}
+
 	fmt.Println("Successfully loaded your repository and private key\nConfig file written to your systems config folder as gpass/config.json")
 	return nil
 }
+
+// generate creates a new passphrase protected private key, stores it at c.key and returns the passphrase
+func (c *InitCmd) generate(u *git.User) ([]byte, error) {
+	if c.name == "" {
+		c.name = u.Name
+	}
+
+	if c.email == "" {
+		c.email = u.Email
+	}
+
+	if c.name == "" || c.email == "" {
+		return nil, fmt.Errorf("please provide a --name and --email for the new key")
+	}
+
+	if c.key == "" {
+		c.key = path.Join(u.HomeFolder, ".gpass", "private-key.asc")
+	}
+
+	var prompts []string
+	prompts = append(prompts, "Enter passphrase for the new key: ")
+	prompts = append(prompts, "Retype passphrase for the new key: ")
+
+	p, err := utils.PassShellPrompt(prompts)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Println("Generating a new RSA 4096 key pair, this may take a moment...")
+
+	k, err := encrypt.GenerateKey(c.name, c.email, p)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := utils.WritePrivateFile(c.key, k); err != nil {
+		return nil, err
+	}
+
+	fmt.Println("Private key written to", c.key)
+
+	return p, nil
+}
@@ -1,14 +1,18 @@
 package cmd
 
 import (
+	"bytes"
 	"fmt"
 
-	"github.com/eiso/gpass/encrypt"
+	"github.com/eiso/gpass/git"
 	"github.com/eiso/gpass/utils"
 	"github.com/spf13/cobra"
 )
 
-type InsertCmd struct{}
+type InsertCmd struct {
+	multiline bool
+	editor    bool
+}
 
 func NewInsertCmd() *InsertCmd {
 	return &InsertCmd{}
@@ -21,6 +25,9 @@ func (c *InsertCmd) Cmd() *cobra.Command {
 		RunE:  c.Execute,
 	}
 
+	cmd.Flags().BoolVarP(&c.multiline, "multiline", "m", false, "Read the account contents from stdin until EOF.")
+	cmd.Flags().BoolVarP(&c.editor, "editor", "e", false, "Write the account contents in your $EDITOR.")
+
 	return cmd
 }
 
@@ -34,6 +41,10 @@ func (c *InsertCmd) Execute(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("please provide a name for the account you are inserting")
 	}
 
+	if c.multiline && c.editor {
+		return fmt.Errorf("please use either --multiline or --editor, not both")
+	}
+
 	var prompts []string
 	var path string
 	var filename string
@@ -51,19 +62,42 @@ func (c *InsertCmd) Execute(cmd *cobra.Command, args []string) error {
 	}
 
 	if !r.BranchExists("gpass") {
-		return fmt.Errorf("gpass has not been initialized yet, please run: gpass init")
+		return notInitialized(r)
 	}
 
 	if r.BranchExists(filename) {
 		return fmt.Errorf("the account already exists")
 	}
 
-	f, err := utils.PassShellPrompt(prompts)
+	var f []byte
+	var err error
+
+	switch {
+	case c.multiline:
+		m := fmt.Sprintf("Enter contents of %s and press Ctrl+D when finished:", path)
+		f, err = utils.MultilineShellPrompt(m)
+	case c.editor:
+		f, err = utils.EditorShellPrompt(nil)
+	default:
+		f, err = utils.PassShellPrompt(prompts)
+	}
 	if err != nil {
 		return err
 	}
 
-	if err := r.CheckoutBranch("gpass"); err != nil {
+	if len(bytes.TrimSpace(f)) == 0 {
+		return fmt.Errorf("the account contents are empty, nothing was inserted")
+	}
+
+	return insertAccount(r, path, f)
+}
+
+// insertAccount encrypts a message and commits it on a new branch for the account
+func insertAccount(r *git.Repository, account string, m []byte) error {
+	filename := account + ".gpg"
+
+	p, err := unlockKey(m, false)
+	if err != nil {
 		return err
 	}
 
@@ -72,19 +106,12 @@ func (c *InsertCmd) Execute(cmd *cobra.Command, args []string) error {
 			return err
 		}
 	} else {
-		if err := r.TagBranch(filename, true); err != nil {
+		if err := r.TagBranch(filename); err != nil {
 			return err
 		}
 	}
 
-	pk, err := utils.LoadFile(Cfg.PrivateKey)
-	if err != nil {
-		return err
-	}
-
-	p := encrypt.NewPGP(pk, f, false)
-
-	if err := p.LoadKeys(); err != nil {
+	if err := loadRecipients(r, p, account); err != nil {
 		return err
 	}
 
@@ -92,12 +119,8 @@ func (c *InsertCmd) Execute(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	if err := p.WriteFile(r.Path, filename); err != nil {
-		return err
-	}
-
-	msg := fmt.Sprintf("Add: %s", path)
-	if err := r.CommitFile(Cfg.User, filename, msg); err != nil {
+	msg := fmt.Sprintf("Add: %s", account)
+	if err := r.WriteFile(Cfg.User, filename, filename, p.Message, msg); err != nil {
 		return err
 	}
 
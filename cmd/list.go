@@ -5,6 +5,7 @@ import (
 	"os"
 	"strings"
 
+	"github.com/eiso/gpass/git"
 	"github.com/eiso/treeprint"
 	"github.com/spf13/cobra"
 )
@@ -37,27 +38,45 @@ func (c *ListCmd) Execute(cmd *cobra.Command, args []string) error {
 	}
 
 	if !r.BranchExists("gpass") {
-		return fmt.Errorf("gpass has not been initialized yet, please run: gpass init")
+		return notInitialized(r)
 	}
 
-	tree := treeprint.New()
-	b := r.ListBranches()
+	b := listAccounts(r)
 
-	if len(b) <= 1 {
+	if len(b) < 1 {
 		return fmt.Errorf("nothing to list here, no accounts have been added yet")
 	}
 
-	for _, branch := range b {
-		if branch == "gpass" {
+	var accounts []string
+	for _, a := range b {
+		if len(args) > 0 && !strings.HasPrefix(a, args[0]) {
 			continue
 		}
 
-		branch = branch[:len(branch)-4]
+		accounts = append(accounts, a)
+	}
 
-		if len(args) > 0 && !strings.HasPrefix(branch, args[0]){
-			continue
-		}
+	printTree(accounts)
+
+	return nil
+}
+
+// listAccounts returns the names of all accounts in the repository
+func listAccounts(r *git.Repository) []string {
+	var accounts []string
 
+	for _, branch := range r.ListAccounts() {
+		accounts = append(accounts, strings.TrimSuffix(branch, ".gpg"))
+	}
+
+	return accounts
+}
+
+// printTree prints the accounts as a tree of their paths
+func printTree(accounts []string) {
+	tree := treeprint.New()
+
+	for _, branch := range accounts {
 		parts := strings.Split(branch, string(os.PathSeparator))
 
 		t := tree.FindByValue(parts[0])
@@ -79,6 +98,4 @@ func (c *ListCmd) Execute(cmd *cobra.Command, args []string) error {
 	}
 
 	fmt.Println(tree.String())
-
-	return nil
 }
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/eiso/gpass/agent"
+	"github.com/spf13/cobra"
+)
+
+type LockCmd struct{}
+
+func NewLockCmd() *LockCmd {
+	return &LockCmd{}
+}
+
+func (c *LockCmd) Cmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lock",
+		Args:  cobra.NoArgs,
+		Short: "Wipes the private key from the agent and stops it.",
+		RunE:  c.Execute,
+	}
+
+	return cmd
+}
+
+func (c *LockCmd) Execute(cmd *cobra.Command, args []string) error {
+	a, err := agent.Dial()
+	if err != nil {
+		fmt.Println("No agent is running")
+		return nil
+	}
+
+	if err := a.Lock(); err != nil {
+		return err
+	}
+
+	fmt.Println("The agent has wiped the key")
+
+	return nil
+}
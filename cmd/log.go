@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+type LogCmd struct{}
+
+func NewLogCmd() *LogCmd {
+	return &LogCmd{}
+}
+
+func (c *LogCmd) Cmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "log",
+		Args:  cobra.ExactArgs(1),
+		Short: "Shows the history of an account, including removed accounts.",
+		RunE:  c.Execute,
+	}
+
+	return cmd
+}
+
+func (c *LogCmd) Execute(cmd *cobra.Command, args []string) error {
+	if err := InitCheck(); err != nil {
+		return err
+	}
+
+	if len(args) != 1 {
+		return fmt.Errorf("please provide the name of the account")
+	}
+
+	r := Cfg.Repository
+	filename := args[0] + ".gpg"
+
+	if err := r.Load(); err != nil {
+		return err
+	}
+
+	if !r.BranchExists("gpass") {
+		return notInitialized(r)
+	}
+
+	if !r.BranchExists(filename) && !r.TagExists(filename) {
+		return fmt.Errorf("%s does not exist and has never been removed", args[0])
+	}
+
+	h, err := r.History(filename)
+	if err != nil {
+		return err
+	}
+
+	if !r.BranchExists(filename) {
+		fmt.Printf("%s has been removed, showing its history up to the removal\n\n", args[0])
+	}
+
+	for _, rev := range h {
+		fmt.Printf("commit %s\n", rev.Hash)
+		fmt.Printf("Author: %s <%s>\n", rev.Author, rev.Email)
+		fmt.Printf("Date:   %s\n\n", rev.When.Format("Mon Jan 2 15:04:05 2006 -0700"))
+		fmt.Printf("    %s\n\n", strings.Replace(strings.TrimSpace(rev.Message), "\n", "\n    ", -1))
+	}
+
+	return nil
+}
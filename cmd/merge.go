@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"strings"
+)
+
+// fieldState is the value of a single part of an entry in one revision
+type fieldState struct {
+	value  string
+	exists bool
+}
+
+// fieldMerge is the three-way state of a single part of an entry: the password,
+// a `key: value` field or the free form notes
+type fieldMerge struct {
+	key    string
+	base   fieldState
+	local  fieldState
+	remote fieldState
+}
+
+// conflict returns true if both sides changed the field differently
+func (f *fieldMerge) conflict() bool {
+	return f.local != f.remote && f.local != f.base && f.remote != f.base
+}
+
+// merged returns the value taking the changed side, or the local side on conflicts
+func (f *fieldMerge) merged() fieldState {
+	if f.local == f.base {
+		return f.remote
+	}
+
+	return f.local
+}
+
+// mergeFields returns the three-way state of every part of the entries, in the order of
+// local followed by parts only present remotely or in the base. A nil entry has no parts.
+func mergeFields(base *entry, local *entry, remote *entry) []*fieldMerge {
+	var order []string
+	states := make(map[string]*fieldMerge)
+
+	get := func(key string) *fieldMerge {
+		f, ok := states[key]
+		if !ok {
+			f = &fieldMerge{key: key}
+			states[key] = f
+			order = append(order, key)
+		}
+		return f
+	}
+
+	for _, e := range []*entry{local, remote, base} {
+		if e == nil {
+			continue
+		}
+
+		for _, fl := range entryFields(e) {
+			s := fieldState{fl.value, true}
+			f := get(fl.key)
+
+			switch e {
+			case local:
+				f.local = s
+			case remote:
+				f.remote = s
+			case base:
+				f.base = s
+			}
+		}
+	}
+
+	var res []*fieldMerge
+	for _, k := range order {
+		res = append(res, states[k])
+	}
+
+	return res
+}
+
+// entryFields splits an entry into its password, fields keyed by lower case name and notes
+func entryFields(e *entry) []field {
+	f := []field{{key: "password", value: e.password(), line: 1}}
+	keys := map[string]bool{"password": true, "notes": true}
+	used := make(map[int]bool)
+
+	for _, fl := range e.fields {
+		k := strings.ToLower(fl.key)
+		if keys[k] {
+			continue
+		}
+		keys[k] = true
+		used[fl.line] = true
+
+		f = append(f, field{key: k, value: fl.value, line: fl.line})
+	}
+
+	var notes []string
+	for i, l := range e.lines {
+		if i == 0 || used[i+1] || strings.TrimSpace(l) == "" {
+			continue
+		}
+		notes = append(notes, l)
+	}
+
+	if len(notes) > 0 {
+		f = append(f, field{key: "notes", value: strings.Join(notes, "\n")})
+	}
+
+	return f
+}
+
+// fieldLines returns the line numbers, starting at 1, of every part of an entry as split by entryFields
+func fieldLines(e *entry) map[string][]int {
+	lines := make(map[string][]int)
+	used := make(map[int]bool)
+
+	for _, f := range entryFields(e) {
+		if f.key == "notes" {
+			continue
+		}
+		lines[f.key] = []int{f.line}
+		used[f.line] = true
+	}
+
+	for i, l := range e.lines {
+		if used[i+1] || strings.TrimSpace(l) == "" {
+			continue
+		}
+		lines["notes"] = append(lines["notes"], i+1)
+	}
+
+	return lines
+}
+
+// mergeEntry builds the message of a merged entry from the lines of the local entry, only the parts whose
+// merged value differs from the local one are replaced by their original lines in the remote entry. Fields
+// only present remotely follow the last local field and notes only present remotely end the entry.
+func mergeEntry(local *entry, remote *entry, fields []*fieldMerge) []byte {
+	if local == nil {
+		local = parseEntry(nil)
+	}
+
+	ll := fieldLines(local)
+	rl := make(map[string][]int)
+	if remote != nil {
+		rl = fieldLines(remote)
+	}
+
+	edits := make(map[int][]string)
+	var added, notes []string
+
+	for _, f := range fields {
+		m := f.merged()
+		if m == f.local {
+			continue
+		}
+
+		var with []string
+		if m.exists {
+			for _, n := range rl[f.key] {
+				with = append(with, remote.lines[n-1])
+			}
+		}
+
+		at := ll[f.key]
+		switch {
+		case len(at) > 0:
+			edits[at[0]] = with
+			for _, n := range at[1:] {
+				edits[n] = nil
+			}
+		case f.key == "notes":
+			notes = with
+		default:
+			added = append(added, with...)
+		}
+	}
+
+	// the last line of the fields and of the content, trailing blank lines are kept after them
+	fieldsEnd, contentEnd := 1, 1
+	for k, ns := range ll {
+		if k != "notes" && ns[0] > fieldsEnd {
+			fieldsEnd = ns[0]
+		}
+	}
+	for i, l := range local.lines {
+		if strings.TrimSpace(l) != "" && i+1 > contentEnd {
+			contentEnd = i + 1
+		}
+	}
+
+	var lines []string
+	for i, l := range local.lines {
+		if with, ok := edits[i+1]; ok {
+			lines = append(lines, with...)
+		} else {
+			lines = append(lines, l)
+		}
+
+		if i+1 == fieldsEnd {
+			lines = append(lines, added...)
+		}
+		if i+1 == contentEnd {
+			lines = append(lines, notes...)
+		}
+	}
+
+	return []byte(strings.Join(lines, "\n"))
+}
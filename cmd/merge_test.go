@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeFields(t *testing.T) {
+	base := parseEntry([]byte("hunter2\nusername: eiso\nurl: https://github.com\n"))
+	local := parseEntry([]byte("hunter3\nusername: eiso\nurl: https://github.com\n"))
+	remote := parseEntry([]byte("hunter2\nusername: eiso\nurl: https://github.com/login\notp: 123\nrecovery codes\n"))
+
+	fields := mergeFields(base, local, remote)
+
+	for _, f := range fields {
+		require.False(t, f.conflict(), f.key)
+	}
+
+	require.Equal(t, "hunter3\nusername: eiso\nurl: https://github.com/login\notp: 123\nrecovery codes\n", string(mergeEntry(local, remote, fields)))
+
+	remote = parseEntry([]byte("hunter4\nusername: eiso\n"))
+	fields = mergeFields(base, local, remote)
+
+	var conflicts []string
+	for _, f := range fields {
+		if f.conflict() {
+			conflicts = append(conflicts, f.key)
+		}
+	}
+
+	require.Equal(t, []string{"password"}, conflicts)
+	require.Equal(t, "hunter3\nusername: eiso\n", string(mergeEntry(local, remote, fields)))
+
+	fields = mergeFields(nil, local, remote)
+	require.True(t, fields[0].conflict())
+
+	// the lines of the local entry are kept as they are, only the changed field is replaced
+	base = parseEntry([]byte("hunter2\nURL: https://acme.com\n\notpauth://totp/acme?secret=ABC\nsecond note\n"))
+	local = parseEntry([]byte("hunter2\nURL: https://acme.com\n\notpauth://totp/acme?secret=ABC\nsecond note\n"))
+	remote = parseEntry([]byte("hunter2\nURL:   https://acme.com/login\n\notpauth://totp/acme?secret=ABC\nsecond note\n"))
+	fields = mergeFields(base, local, remote)
+	require.Equal(t, "hunter2\nURL:   https://acme.com/login\n\notpauth://totp/acme?secret=ABC\nsecond note\n", string(mergeEntry(local, remote, fields)))
+
+	// changed notes replace the local notes in place
+	remote = parseEntry([]byte("hunter2\nURL: https://acme.com\n\notpauth://totp/acme?secret=XYZ\n"))
+	fields = mergeFields(base, local, remote)
+	require.Equal(t, "hunter2\nURL: https://acme.com\n\notpauth://totp/acme?secret=XYZ\n", string(mergeEntry(local, remote, fields)))
+}
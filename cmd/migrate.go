@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+type MigrateCmd struct{}
+
+func NewMigrateCmd() *MigrateCmd {
+	return &MigrateCmd{}
+}
+
+func (c *MigrateCmd) Cmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Args:  cobra.NoArgs,
+		Short: "Moves a store created by an older version of gpass out of the regular branches and tags.",
+		RunE:  c.Execute,
+	}
+
+	return cmd
+}
+
+func (c *MigrateCmd) Execute(cmd *cobra.Command, args []string) error {
+	if err := InitCheck(); err != nil {
+		return err
+	}
+
+	r := Cfg.Repository
+
+	if err := r.Load(); err != nil {
+		return err
+	}
+
+	if !r.Legacy() {
+		fmt.Println("The store does not need to be migrated")
+		return nil
+	}
+
+	if r.BranchExists("gpass") {
+		return fmt.Errorf("the store has been migrated already but a gpass branch still exists, please remove it with git")
+	}
+
+	res, err := r.Migrate()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Successfully migrated %d references to refs/gpass/\n", res.Moved)
+	if res.Detached != "" {
+		fmt.Printf("HEAD was on a gpass branch and has been detached at %s, run git checkout to return to one of your branches\n", res.Detached[:7])
+	}
+	fmt.Println("Run gpass push to publish the store, the old branches and tags on remotes are left in place")
+
+	return nil
+}
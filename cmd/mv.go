@@ -2,9 +2,7 @@ package cmd
 
 import (
 	"fmt"
-	"path"
 
-	"github.com/eiso/gpass/utils"
 	"github.com/spf13/cobra"
 )
 
@@ -45,15 +43,13 @@ func (c *MvCmd) Execute(cmd *cobra.Command, args []string) error {
 
 	filename := args[0] + ".gpg"
 	new := args[1] + ".gpg"
-	pathPrev := path.Join(r.Path, filename)
-	pathNew := path.Join(r.Path, new)
 
 	if err := r.Load(); err != nil {
 		return err
 	}
 
 	if !r.BranchExists("gpass") {
-		return fmt.Errorf("gpass has not been initialized yet, please run: gpass init")
+		return notInitialized(r)
 	}
 
 	if !r.BranchExists(filename) {
@@ -64,24 +60,38 @@ func (c *MvCmd) Execute(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("%s already exists", args[1])
 	}
 
-	if err := r.CreateBranch(filename, new); err != nil {
+	p, err := unlockKey(nil, true)
+	if err != nil {
 		return err
 	}
 
-	if err := r.RemoveBranch(filename); err != nil {
+	m, err := reencryptTo(r, p, args[0], args[1])
+	if err != nil {
 		return err
 	}
 
-	if err := utils.RenamePath(pathPrev, pathNew); err != nil {
+	if err := r.CreateBranch(filename, new); err != nil {
 		return err
 	}
 
-	if err := utils.DeleteEmptyFolders(r.Path); err != nil {
+	msg := fmt.Sprintf("Moved: %s to %s", args[0], args[1])
+	if err := r.MoveFile(Cfg.User, new, filename, new, msg); err != nil {
 		return err
 	}
 
-	msg := fmt.Sprintf("Moved: %s to %s", args[0], args[1])
-	if err := r.CommitFile(Cfg.User, new, msg); err != nil {
+	if m != nil {
+		msg := fmt.Sprintf("Re-encrypt: %s for its new recipients", args[1])
+		if err := r.WriteFile(Cfg.User, new, new, m, msg); err != nil {
+			return err
+		}
+	}
+
+	// the old branch is only removed once the moved account has been committed
+	if err := r.RemoveBranch(filename); err != nil {
+		return err
+	}
+
+	if err := r.RecordMove(Cfg.User, filename, new); err != nil {
 		return err
 	}
 
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+type PullCmd struct {
+	remote   string
+	insecure bool
+}
+
+func NewPullCmd() *PullCmd {
+	return &PullCmd{}
+}
+
+func (c *PullCmd) Cmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "pull",
+		Args:  cobra.NoArgs,
+		Short: "Pulls all accounts and their history from a remote.",
+		RunE:  c.Execute,
+	}
+
+	cmd.Flags().StringVarP(&c.remote, "remote", "r", "origin", "The git remote to pull from.")
+	cmd.Flags().BoolVar(&c.insecure, "insecure", false, "Only warn about incoming changes that are not signed by a trusted signer.")
+
+	return cmd
+}
+
+func (c *PullCmd) Execute(cmd *cobra.Command, args []string) error {
+	if err := InitCheck(); err != nil {
+		return err
+	}
+
+	r := Cfg.Repository
+
+	if err := r.Load(); err != nil {
+		return err
+	}
+
+	if !r.BranchExists("gpass") {
+		return notInitialized(r)
+	}
+
+	if err := r.Fetch(c.remote); err != nil {
+		return err
+	}
+
+	res, err := fastForward(r, c.remote, c.insecure, "pull from")
+	if err != nil {
+		return err
+	}
+
+	for _, b := range res.Updated {
+		fmt.Println("Updated", b)
+	}
+
+	for _, b := range res.Diverged {
+		fmt.Printf("%s has diverged from %s, run: gpass sync\n", b, c.remote)
+	}
+
+	for _, b := range res.RemovedDiverged {
+		fmt.Printf("The removal of %s has diverged from %s and was not pulled\n", strings.TrimSuffix(b, ".gpg"), c.remote)
+	}
+
+	if err := replayJournal(r); err != nil {
+		return err
+	}
+
+	fmt.Println("Successfully pulled from", c.remote)
+
+	return nil
+}
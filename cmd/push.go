@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+type PushCmd struct {
+	remote string
+}
+
+func NewPushCmd() *PushCmd {
+	return &PushCmd{}
+}
+
+func (c *PushCmd) Cmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "push",
+		Args:  cobra.NoArgs,
+		Short: "Pushes all accounts and their history to a remote.",
+		RunE:  c.Execute,
+	}
+
+	cmd.Flags().StringVarP(&c.remote, "remote", "r", "origin", "The git remote to push to.")
+
+	return cmd
+}
+
+func (c *PushCmd) Execute(cmd *cobra.Command, args []string) error {
+	if err := InitCheck(); err != nil {
+		return err
+	}
+
+	r := Cfg.Repository
+
+	if err := r.Load(); err != nil {
+		return err
+	}
+
+	if !r.BranchExists("gpass") {
+		return notInitialized(r)
+	}
+
+	// fetch first so branches that changed on the remote are not overwritten
+	if err := r.Fetch(c.remote); err != nil {
+		return err
+	}
+
+	skipped, err := r.Push(c.remote)
+	if err != nil {
+		return err
+	}
+
+	for _, b := range skipped {
+		fmt.Printf("%s has changes on %s that are not local, run: gpass sync\n", b, c.remote)
+	}
+
+	fmt.Println("Successfully pushed to", c.remote)
+
+	return nil
+}
@@ -0,0 +1,298 @@
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/eiso/gpass/encrypt"
+	"github.com/eiso/gpass/git"
+	"github.com/eiso/gpass/utils"
+	"github.com/spf13/cobra"
+)
+
+// recipientsFile is the keyring on the gpass branch holding the public keys accounts are encrypted to
+const recipientsFile = ".recipients"
+
+type RecipientsCmd struct {
+	folder string
+}
+
+func NewRecipientsCmd() *RecipientsCmd {
+	return &RecipientsCmd{}
+}
+
+func (c *RecipientsCmd) Cmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "recipients",
+		Short: "Manages the public keys that accounts are encrypted to.",
+	}
+
+	cmd.PersistentFlags().StringVarP(&c.folder, "folder", "f", "", "Manage the recipients of a folder, overriding those of its parents.")
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "Lists the recipients of the store.",
+		Args:  cobra.NoArgs,
+		RunE:  c.List,
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "add /path/to/public-key.asc...",
+		Short: "Adds armored public keys to the recipients of the store.",
+		Args:  cobra.MinimumNArgs(1),
+		RunE:  c.Add,
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "remove key-id|email",
+		Short: "Removes a recipient from the store.",
+		Args:  cobra.ExactArgs(1),
+		RunE:  c.Remove,
+	})
+
+	return cmd
+}
+
+func (c *RecipientsCmd) List(cmd *cobra.Command, args []string) error {
+	rc, err := c.load()
+	if err != nil {
+		return err
+	}
+
+	if rc.Len() == 0 && c.folder != "" {
+		fmt.Printf("%s has no recipients of its own and uses those of its parent folders\n", c.folder)
+		return nil
+	}
+
+	if rc.Len() == 0 {
+		fmt.Println("No recipients have been added yet, accounts are only encrypted to your own key")
+		return nil
+	}
+
+	for _, l := range rc.List() {
+		fmt.Println(l)
+	}
+
+	return nil
+}
+
+func (c *RecipientsCmd) Add(cmd *cobra.Command, args []string) error {
+	rc, err := c.load()
+	if err != nil {
+		return err
+	}
+
+	p, err := unlockKey(nil, false)
+	if err != nil {
+		return err
+	}
+
+	// a new recipients file starts out with the user's own key so the user does not lock themselves out
+	if rc.Len() == 0 {
+		k, err := p.PublicKey()
+		if err != nil {
+			return err
+		}
+
+		if _, err := rc.Add(k); err != nil {
+			return err
+		}
+	}
+
+	var added []string
+
+	for _, a := range args {
+		f, err := utils.LoadFile(a)
+		if err != nil {
+			return err
+		}
+
+		ids, err := rc.Add(f)
+		if err != nil {
+			return fmt.Errorf("%s: %s", a, err)
+		}
+
+		added = append(added, ids...)
+	}
+
+	if len(added) == 0 {
+		fmt.Println("All keys are recipients already")
+		return nil
+	}
+
+	msg := fmt.Sprintf("Add recipients: %v", added)
+	if c.folder != "" {
+		msg = fmt.Sprintf("Add recipients to %s: %v", c.folder, added)
+	}
+
+	if err := c.save(rc, msg); err != nil {
+		return err
+	}
+
+	for _, id := range added {
+		fmt.Println("Added recipient", id)
+	}
+
+	return nil
+}
+
+func (c *RecipientsCmd) Remove(cmd *cobra.Command, args []string) error {
+	rc, err := c.load()
+	if err != nil {
+		return err
+	}
+
+	d, err := rc.Remove(args[0])
+	if err != nil {
+		return err
+	}
+
+	if rc.Len() == 0 && c.folder == "" {
+		return fmt.Errorf("unable to remove the last recipient of the store")
+	}
+
+	m := fmt.Sprintf("Are you sure you would like to remove %s?", d)
+	if !utils.ConfirmShellPrompt(m) {
+		return nil
+	}
+
+	if _, err := unlockKey(nil, false); err != nil {
+		return err
+	}
+
+	msg := fmt.Sprintf("Remove recipient: %s", d)
+	if c.folder != "" {
+		msg = fmt.Sprintf("Remove recipient from %s: %s", c.folder, d)
+	}
+
+	if err := c.save(rc, msg); err != nil {
+		return err
+	}
+
+	fmt.Println("Removed recipient", d)
+	fmt.Println("Existing accounts stay readable by this key until you run: gpass reencrypt")
+
+	return nil
+}
+
+func (c *RecipientsCmd) load() (*encrypt.Recipients, error) {
+	if err := InitCheck(); err != nil {
+		return nil, err
+	}
+
+	r := Cfg.Repository
+
+	if err := r.Load(); err != nil {
+		return nil, err
+	}
+
+	if !r.BranchExists("gpass") {
+		return nil, notInitialized(r)
+	}
+
+	return readRecipients(r, c.file())
+}
+
+func (c *RecipientsCmd) save(rc *encrypt.Recipients, msg string) error {
+	r := Cfg.Repository
+	file := c.file()
+
+	// a folder without recipients falls back to the recipients of its parents
+	if rc.Len() == 0 {
+		return r.RemoveFile(Cfg.User, "gpass", file, msg)
+	}
+
+	b, err := rc.Bytes()
+	if err != nil {
+		return err
+	}
+
+	return r.WriteFile(Cfg.User, "gpass", file, b, msg)
+}
+
+// file returns the path of the recipients file on the gpass branch managed by the command
+func (c *RecipientsCmd) file() string {
+	return path.Join(strings.Trim(c.folder, "/"), recipientsFile)
+}
+
+// recipientsPath returns the most specific recipients file on the gpass branch for an account,
+// walking up from the account's folder to the root of the store
+func recipientsPath(r *git.Repository, account string) string {
+	dir := path.Dir(account)
+
+	for dir != "." && dir != "/" {
+		f := path.Join(dir, recipientsFile)
+		if r.FileExists("gpass", f) {
+			return f
+		}
+
+		dir = path.Dir(dir)
+	}
+
+	return recipientsFile
+}
+
+// readRecipients reads a recipients file from the gpass branch
+func readRecipients(r *git.Repository, file string) (*encrypt.Recipients, error) {
+	if !r.FileExists("gpass", file) {
+		return encrypt.NewRecipients(nil)
+	}
+
+	b, err := r.ReadFile("gpass", file)
+	if err != nil {
+		return nil, err
+	}
+
+	return encrypt.NewRecipients(b)
+}
+
+// loadRecipients sets the recipients p encrypts an account to
+func loadRecipients(r *git.Repository, p *encrypt.PGP, account string) error {
+	rc, err := readRecipients(r, recipientsPath(r, account))
+	if err != nil {
+		return err
+	}
+
+	p.Recipients = rc
+
+	return nil
+}
+
+// reencryptTo returns the ciphertext of an account re-encrypted to the recipients of its new path,
+// or nil if both paths use the same recipients file. Committed on the moved or copied branch, it keeps
+// future changes from the recipients of the old folder only, the history the branch carries along
+// still holds the ciphertexts encrypted to them.
+func reencryptTo(r *git.Repository, p *encrypt.PGP, account string, to string) ([]byte, error) {
+	if recipientsPath(r, account) == recipientsPath(r, to) {
+		return nil, nil
+	}
+
+	filename := account + ".gpg"
+
+	f, err := r.ReadFile(filename, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	p.Message = f
+	p.Encrypted = true
+
+	if err := loadRecipients(r, p, account); err != nil {
+		return nil, err
+	}
+
+	if err := p.Decrypt(); err != nil {
+		return nil, err
+	}
+
+	if err := loadRecipients(r, p, to); err != nil {
+		return nil, err
+	}
+
+	if err := p.Encrypt(); err != nil {
+		return nil, err
+	}
+
+	return p.Message, nil
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/eiso/gpass/git"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecipientsPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gpass-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	u := &git.User{Name: "John Doe", Email: "john@doe.org"}
+	r := &git.Repository{Path: dir}
+	require.NoError(t, r.Create())
+	require.NoError(t, r.CreateOrphanBranch(u, "gpass"))
+
+	require.Equal(t, recipientsFile, recipientsPath(r, "bank"))
+	require.Equal(t, recipientsFile, recipientsPath(r, "infra/db"))
+
+	require.NoError(t, r.WriteFile(u, "gpass", recipientsFile, []byte("root"), "add"))
+	require.NoError(t, r.WriteFile(u, "gpass", "infra/.recipients", []byte("infra"), "add"))
+	require.NoError(t, r.WriteFile(u, "gpass", "infra/prod/eu/.recipients", []byte("eu"), "add"))
+
+	require.Equal(t, recipientsFile, recipientsPath(r, "bank"))
+	require.Equal(t, recipientsFile, recipientsPath(r, "finance/db"))
+	require.Equal(t, "infra/.recipients", recipientsPath(r, "infra/db"))
+	require.Equal(t, "infra/.recipients", recipientsPath(r, "infra/prod/db"))
+	require.Equal(t, "infra/prod/eu/.recipients", recipientsPath(r, "infra/prod/eu/db"))
+	require.Equal(t, "infra/prod/eu/.recipients", recipientsPath(r, "infra/prod/eu/web/db"))
+
+	// a folder whose recipients file was removed falls back to its parents
+	require.NoError(t, r.RemoveFile(u, "gpass", "infra/.recipients", "remove"))
+	require.Equal(t, recipientsFile, recipientsPath(r, "infra/db"))
+	require.Equal(t, "infra/prod/eu/.recipients", recipientsPath(r, "infra/prod/eu/db"))
+}
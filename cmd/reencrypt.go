@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/eiso/gpass/encrypt"
+	"github.com/spf13/cobra"
+)
+
+type ReencryptCmd struct {
+	insecure bool
+}
+
+func NewReencryptCmd() *ReencryptCmd {
+	return &ReencryptCmd{}
+}
+
+func (c *ReencryptCmd) Cmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "reencrypt [subtree]",
+		Args:  cobra.MaximumNArgs(1),
+		Short: "Re-encrypts all accounts to their current recipients.",
+		RunE:  c.Execute,
+	}
+
+	cmd.Flags().BoolVar(&c.insecure, "insecure", false, "Also re-encrypt and sign accounts that are not signed by a trusted recipient.")
+
+	return cmd
+}
+
+func (c *ReencryptCmd) Execute(cmd *cobra.Command, args []string) error {
+	if err := InitCheck(); err != nil {
+		return err
+	}
+
+	r := Cfg.Repository
+
+	if err := r.Load(); err != nil {
+		return err
+	}
+
+	if !r.BranchExists("gpass") {
+		return notInitialized(r)
+	}
+
+	var accounts []string
+	for _, a := range listAccounts(r) {
+		if len(args) > 0 && a != args[0] && !strings.HasPrefix(a, strings.TrimSuffix(args[0], "/")+"/") {
+			continue
+		}
+		accounts = append(accounts, a)
+	}
+
+	if len(accounts) == 0 {
+		return fmt.Errorf("no accounts to re-encrypt")
+	}
+
+	p, err := unlockKey(nil, true)
+	if err != nil {
+		return err
+	}
+	p.Insecure = c.insecure
+
+	var failed int
+
+	for i, account := range accounts {
+		fmt.Printf("[%d/%d] %s: ", i+1, len(accounts), account)
+
+		done, err := c.reencrypt(p, account)
+		switch {
+		case err != nil:
+			failed++
+			fmt.Println("failed,", err)
+		case !done:
+			fmt.Println("already encrypted to the current recipients")
+		default:
+			fmt.Println("re-encrypted")
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d accounts failed to re-encrypt, run the command again to retry them", failed, len(accounts))
+	}
+
+	return nil
+}
+
+// reencrypt encrypts an account to its current recipients and commits it, accounts that are already
+// encrypted to the current recipients and signed by one of them are skipped so an interrupted run can be resumed
+func (c *ReencryptCmd) reencrypt(p *encrypt.PGP, account string) (bool, error) {
+	r := Cfg.Repository
+	filename := account + ".gpg"
+
+	f, err := r.ReadFile(filename, filename)
+	if err != nil {
+		return false, err
+	}
+
+	p.Message = f
+	p.Encrypted = true
+
+	if err := loadRecipients(r, p, account); err != nil {
+		return false, err
+	}
+
+	ok, err := p.UpToDate()
+	if err != nil {
+		return false, err
+	}
+
+	if err := p.Decrypt(); err != nil {
+		return false, err
+	}
+
+	if ok && p.Author != nil {
+		return false, nil
+	}
+
+	if err := p.Encrypt(); err != nil {
+		return false, err
+	}
+
+	msg := fmt.Sprintf("Re-encrypt: %s", account)
+	if err := r.WriteFile(Cfg.User, filename, filename, p.Message, msg); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
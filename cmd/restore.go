@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/eiso/gpass/git"
+	"github.com/eiso/gpass/utils"
+	"github.com/spf13/cobra"
+)
+
+type RestoreCmd struct {
+	rev      string
+	insecure bool
+}
+
+func NewRestoreCmd() *RestoreCmd {
+	return &RestoreCmd{}
+}
+
+func (c *RestoreCmd) Cmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "restore",
+		Args:  cobra.ExactArgs(1),
+		Short: "Restores a removed account or rolls an account back to an older revision.",
+		RunE:  c.Execute,
+	}
+
+	cmd.Flags().StringVarP(&c.rev, "rev", "r", "", "The revision to restore: n commits ago, a commit hash or a date.")
+	cmd.Flags().BoolVar(&c.insecure, "insecure", false, "Also restore revisions that are not signed by a trusted recipient.")
+
+	return cmd
+}
+
+func (c *RestoreCmd) Execute(cmd *cobra.Command, args []string) error {
+	if err := InitCheck(); err != nil {
+		return err
+	}
+
+	if len(args) != 1 {
+		return fmt.Errorf("please provide a name for the account you are restoring")
+	}
+
+	r := Cfg.Repository
+	filename := args[0] + ".gpg"
+
+	if err := r.Load(); err != nil {
+		return err
+	}
+
+	if !r.BranchExists("gpass") {
+		return notInitialized(r)
+	}
+
+	if r.BranchExists(filename) {
+		return c.rollback(r, args[0])
+	}
+
+	if r.TagExists(filename) {
+		return c.undelete(r, args[0])
+	}
+
+	return fmt.Errorf("%s does not exist and has never been removed", args[0])
+}
+
+// undelete brings back a removed account with its history from the tag left behind by rm
+func (c *RestoreCmd) undelete(r *git.Repository, account string) error {
+	filename := account + ".gpg"
+
+	var rev *git.Revision
+	var f []byte
+
+	if c.rev != "" {
+		var err error
+
+		rev, err = r.Revision(filename, c.rev)
+		if err != nil {
+			return err
+		}
+
+		f, err = r.ReadRevision(rev.Hash, filename)
+		if err != nil {
+			return err
+		}
+	} else {
+		h, err := r.History(filename)
+		if err != nil {
+			return err
+		}
+
+		// the tag points at the removal, restore the last revision that still held the account
+		for _, rv := range h {
+			if f, err = r.ReadRevision(rv.Hash, filename); err == nil {
+				rev = rv
+				break
+			}
+		}
+
+		if rev == nil {
+			return fmt.Errorf("no revision of %s holds any content to restore", account)
+		}
+	}
+
+	m := fmt.Sprintf("Are you sure you would like to restore %s as of %s (%s)?", account, rev.Hash[:7], rev.When.Format("2006-01-02 15:04"))
+	if !utils.ConfirmShellPrompt(m) {
+		return nil
+	}
+
+	f, err := c.reencrypt(r, account, f)
+	if err != nil {
+		return err
+	}
+
+	if err := r.TagBranch(filename); err != nil {
+		return err
+	}
+
+	// the tombstone is kept, it is replicated to the other clones and the restored branch descends
+	// from it so neither the fetch nor the journal removes the account again
+	msg := fmt.Sprintf("Restore: %s as of %s", account, rev.Hash[:7])
+	if err := r.WriteFile(Cfg.User, filename, filename, f, msg); err != nil {
+		return err
+	}
+
+	fmt.Println("Successfully restored the account", account)
+
+	return nil
+}
+
+// rollback commits an older revision of an existing account as its new tip
+func (c *RestoreCmd) rollback(r *git.Repository, account string) error {
+	filename := account + ".gpg"
+
+	if c.rev == "" {
+		return fmt.Errorf("%s exists, please provide the --rev to roll it back to", account)
+	}
+
+	rev, err := r.Revision(filename, c.rev)
+	if err != nil {
+		return err
+	}
+
+	f, err := r.ReadRevision(rev.Hash, filename)
+	if err != nil {
+		return err
+	}
+
+	tip, err := r.ReadFile(filename, filename)
+	if err == nil && bytes.Equal(f, tip) {
+		fmt.Printf("%s already matches %s, nothing to restore\n", account, rev.Hash[:7])
+		return nil
+	}
+
+	m := fmt.Sprintf("Are you sure you would like to roll %s back to %s (%s)?", account, rev.Hash[:7], rev.When.Format("2006-01-02 15:04"))
+	if !utils.ConfirmShellPrompt(m) {
+		return nil
+	}
+
+	f, err = c.reencrypt(r, account, f)
+	if err != nil {
+		return err
+	}
+
+	msg := fmt.Sprintf("Restore: %s to %s", account, rev.Hash[:7])
+	if err := r.WriteFile(Cfg.User, filename, filename, f, msg); err != nil {
+		return err
+	}
+
+	fmt.Println("Successfully restored the account", account, "to", rev.Hash[:7])
+
+	return nil
+}
+
+// reencrypt decrypts a revision of an account and encrypts it to the current recipients of the account,
+// the revision may be encrypted to former recipients and predate signed accounts
+func (c *RestoreCmd) reencrypt(r *git.Repository, account string, f []byte) ([]byte, error) {
+	p, err := unlockKey(f, true)
+	if err != nil {
+		return nil, err
+	}
+	p.Insecure = c.insecure
+
+	if err := loadRecipients(r, p, account); err != nil {
+		return nil, err
+	}
+
+	if err := p.Decrypt(); err != nil {
+		return nil, fmt.Errorf("%s: %s", account, err)
+	}
+
+	if p.Author == nil {
+		fmt.Fprintf(os.Stderr, "warning: %s is not signed by a trusted recipient\n", account)
+	}
+
+	if err := p.Encrypt(); err != nil {
+		return nil, err
+	}
+
+	return p.Message, nil
+}
@@ -2,9 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"os"
-	"path"
-	"strings"
 
 	"github.com/eiso/gpass/utils"
 	"github.com/spf13/cobra"
@@ -37,15 +34,13 @@ func (c *RmCmd) Execute(cmd *cobra.Command, args []string) error {
 
 	r := Cfg.Repository
 	filename := args[0] + ".gpg"
-	d := strings.Split(filename, string(os.PathSeparator))
-	path := path.Join(r.Path, d[0])
 
 	if err := r.Load(); err != nil {
 		return err
 	}
 
 	if !r.BranchExists("gpass") {
-		return fmt.Errorf("gpass has not been initialized yet, please run: gpass init")
+		return notInitialized(r)
 	}
 
 	if !r.BranchExists(filename) {
@@ -59,25 +54,24 @@ func (c *RmCmd) Execute(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	if err := r.CheckoutBranch(filename); err != nil {
+	if _, err := unlockKey(nil, false); err != nil {
 		return err
 	}
 
-	if err := utils.DeletePath(path); err != nil {
+	msg := fmt.Sprintf("Remove: %s", args[0])
+	if err := r.RemoveFile(Cfg.User, filename, filename, msg); err != nil {
 		return err
 	}
 
-	msg := fmt.Sprintf("Remove: %s", args[0])
-	if err := r.Commit(Cfg.User, filename, msg); err != nil {
+	if err := r.AddTagBranch(filename, filename); err != nil {
 		return err
 	}
 
-	n := fmt.Sprintf("refs/tags/%s", filename)
-	if err := r.AddTagBranch(n, filename); err != nil {
+	if err := r.RemoveBranch(filename); err != nil {
 		return err
 	}
 
-	if err := r.RemoveBranch(filename); err != nil {
+	if err := r.RecordRemove(Cfg.User, filename); err != nil {
 		return err
 	}
 
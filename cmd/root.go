@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/eiso/gpass/encrypt"
 	"github.com/eiso/gpass/git"
+	"github.com/eiso/gpass/utils"
 	"github.com/spf13/cobra"
 	"github.com/tucnak/store"
 )
@@ -37,6 +39,43 @@ func InitCheck() error {
 	return nil
 }
 
+// notInitialized returns the error for a repository without a gpass branch
+func notInitialized(r *git.Repository) error {
+	if r.Legacy() {
+		return fmt.Errorf("the store was created by an older version of gpass, please run: gpass migrate")
+	}
+
+	return fmt.Errorf("gpass has not been initialized yet, please run: gpass init")
+}
+
+// unlockKey loads and unlocks the private key for a message and signs the commits of Cfg.User with it
+func unlockKey(m []byte, encrypted bool) (*encrypt.PGP, error) {
+	pk, err := utils.LoadFile(Cfg.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	p := encrypt.NewPGP(pk, m, encrypted)
+
+	if err := p.LoadKeys(); err != nil {
+		return nil, err
+	}
+
+	if err := p.Keyring(3); err == encrypt.ErrNoTerminal {
+		return nil, err
+	} else if err != nil {
+		return nil, fmt.Errorf("[exit] only 3 passphrase attempts allowed")
+	}
+
+	k, err := p.Signer()
+	if err != nil {
+		return nil, err
+	}
+	Cfg.User.SignKey = k
+
+	return p, nil
+}
+
 func init() {
 	store.Init("gpass")
 
@@ -49,6 +88,22 @@ func init() {
 	rootCmd.AddCommand(NewRmCmd().Cmd())
 	rootCmd.AddCommand(NewMvCmd().Cmd())
 	rootCmd.AddCommand(NewCpCmd().Cmd())
+	rootCmd.AddCommand(NewEditCmd().Cmd())
+	rootCmd.AddCommand(NewGenerateCmd().Cmd())
+	rootCmd.AddCommand(NewSearchCmd().Cmd())
+	rootCmd.AddCommand(NewGrepCmd().Cmd())
+	rootCmd.AddCommand(NewRecipientsCmd().Cmd())
+	rootCmd.AddCommand(NewSignersCmd().Cmd())
+	rootCmd.AddCommand(NewReencryptCmd().Cmd())
+	rootCmd.AddCommand(NewLogCmd().Cmd())
+	rootCmd.AddCommand(NewRestoreCmd().Cmd())
+	rootCmd.AddCommand(NewDiffCmd().Cmd())
+	rootCmd.AddCommand(NewPushCmd().Cmd())
+	rootCmd.AddCommand(NewPullCmd().Cmd())
+	rootCmd.AddCommand(NewSyncCmd().Cmd())
+	rootCmd.AddCommand(NewMigrateCmd().Cmd())
+	rootCmd.AddCommand(NewAgentCmd().Cmd())
+	rootCmd.AddCommand(NewLockCmd().Cmd())
 }
 
 // Execute the cobra commands
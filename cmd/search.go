@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+)
+
+type SearchCmd struct {
+	flat bool
+}
+
+func NewSearchCmd() *SearchCmd {
+	return &SearchCmd{}
+}
+
+func (c *SearchCmd) Cmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "search pattern",
+		Args:  cobra.ExactArgs(1),
+		Short: "Searches account names by glob, substring or fuzzy match.",
+		RunE:  c.Execute,
+	}
+
+	cmd.Flags().BoolVarP(&c.flat, "flat", "f", false, "Print the matching accounts as flat paths, best match first.")
+
+	return cmd
+}
+
+func (c *SearchCmd) Execute(cmd *cobra.Command, args []string) error {
+	if err := InitCheck(); err != nil {
+		return err
+	}
+
+	if len(args) != 1 {
+		return fmt.Errorf("please provide a pattern to search for")
+	}
+
+	r := Cfg.Repository
+
+	if err := r.Load(); err != nil {
+		return err
+	}
+
+	if !r.BranchExists("gpass") {
+		return notInitialized(r)
+	}
+
+	accounts := searchAccounts(listAccounts(r), args[0])
+
+	if len(accounts) < 1 {
+		return fmt.Errorf("no accounts match %s", args[0])
+	}
+
+	if c.flat {
+		for _, a := range accounts {
+			fmt.Println(a)
+		}
+		return nil
+	}
+
+	printTree(accounts)
+
+	return nil
+}
+
+// searchAccounts returns the accounts matching pattern ordered from best to worst match.
+// Patterns containing glob characters are matched as a glob, any other pattern
+// matches as a case-insensitive substring or else as a fuzzy subsequence.
+func searchAccounts(accounts []string, pattern string) []string {
+	type match struct {
+		account string
+		score   int
+	}
+
+	var matches []match
+	pattern = strings.ToLower(pattern)
+	glob := strings.ContainsAny(pattern, "*?[")
+
+	for _, a := range accounts {
+		if glob {
+			if ok, _ := path.Match(pattern, strings.ToLower(a)); ok {
+				matches = append(matches, match{a, 0})
+			}
+			continue
+		}
+
+		if s, ok := fuzzyScore(strings.ToLower(a), pattern); ok {
+			matches = append(matches, match{a, s})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return matches[i].account < matches[j].account
+	})
+
+	var result []string
+	for _, m := range matches {
+		result = append(result, m.account)
+	}
+
+	return result
+}
+
+// fuzzyScore returns how well pattern matches s. Substring matches always rank above
+// subsequence matches, and consecutive characters or characters at the start of a
+// path segment increase the score.
+func fuzzyScore(s string, pattern string) (int, bool) {
+	const substring = 1000
+
+	if i := strings.Index(s, pattern); i >= 0 {
+		score := substring - utf8.RuneCountInString(s)
+		if i == 0 || s[i-1] == '/' {
+			score += substring / 2
+		}
+		return score, true
+	}
+
+	score := 0
+	prev := -2
+	p := []rune(pattern)
+	rs := []rune(s)
+	j := 0
+
+	for i, r := range rs {
+		if j == len(p) {
+			break
+		}
+
+		if r != p[j] {
+			continue
+		}
+
+		score++
+		if i == prev+1 {
+			score += 2
+		}
+		if i == 0 || rs[i-1] == '/' {
+			score += 3
+		}
+
+		prev = i
+		j++
+	}
+
+	if j < len(p) {
+		return 0, false
+	}
+
+	return score - len(rs)/4, true
+}
@@ -0,0 +1,17 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchAccounts(t *testing.T) {
+	accounts := []string{"web/github", "web/GitLab", "work/github-enterprise", "mail/gmail", "bank"}
+
+	require.Equal(t, []string{"web/github", "work/github-enterprise"}, searchAccounts(accounts, "*/github*"))
+	require.Equal(t, []string{"web/GitLab"}, searchAccounts(accounts, "gitlab"))
+	require.Equal(t, []string{"web/github", "work/github-enterprise"}, searchAccounts(accounts, "GitHub"))
+	require.ElementsMatch(t, []string{"web/github", "work/github-enterprise", "web/GitLab"}, searchAccounts(accounts, "gt"))
+	require.Empty(t, searchAccounts(accounts, "xyz"))
+}
@@ -2,14 +2,18 @@ package cmd
 
 import (
 	"fmt"
-	"path"
+	"os"
 
 	"github.com/eiso/gpass/encrypt"
-	"github.com/eiso/gpass/utils"
 	"github.com/spf13/cobra"
 )
 
-type ShowCmd struct{}
+type ShowCmd struct {
+	field    string
+	line     int
+	rev      string
+	insecure bool
+}
 
 func NewShowCmd() *ShowCmd {
 	return &ShowCmd{}
@@ -23,6 +27,11 @@ func (c *ShowCmd) Cmd() *cobra.Command {
 		RunE:  c.Execute,
 	}
 
+	cmd.Flags().StringVarP(&c.field, "field", "f", "", "Only show the value of a `key: value` field, e.g. username.")
+	cmd.Flags().IntVarP(&c.line, "line", "l", 0, "Only show a single line, starting at 1 for the password.")
+	cmd.Flags().StringVarP(&c.rev, "rev", "r", "", "Show the account as it was n commits ago, at a commit hash or at a date.")
+	cmd.Flags().BoolVar(&c.insecure, "insecure", false, "Only warn when the account is not signed by a trusted signer or recipient.")
+
 	return cmd
 }
 
@@ -35,51 +44,104 @@ func (c *ShowCmd) Execute(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("please provide a name for the account you are inserting")
 	}
 
+	if c.field != "" && c.line != 0 {
+		return fmt.Errorf("please use either --field or --line, not both")
+	}
+
 	r := Cfg.Repository
 	filename := args[0] + ".gpg"
-	file := path.Join(r.Path, filename)
-
-	pk, err := utils.LoadFile(Cfg.PrivateKey)
-	if err != nil {
-		return err
-	}
 
 	if err := r.Load(); err != nil {
 		return err
 	}
 
 	if !r.BranchExists("gpass") {
-		return fmt.Errorf("gpass has not been initialized yet, please run: gpass init")
+		return notInitialized(r)
 	}
 
-	if !r.BranchExists(filename) {
-		return fmt.Errorf("the account does not exist")
+	signers, err := trustedSigners(r, c.insecure)
+	if err != nil {
+		return err
 	}
 
-	if err := r.CheckoutBranch(filename); err != nil {
-		return err
+	var f []byte
+
+	if c.rev != "" {
+		if !r.BranchExists(filename) && !r.TagExists(filename) {
+			return fmt.Errorf("the account does not exist")
+		}
+
+		rev, err := r.Revision(filename, c.rev)
+		if err != nil {
+			return err
+		}
+
+		_, err = r.VerifyRevision(rev.Hash, signers)
+		if err := untrusted(filename, err, c.insecure); err != nil {
+			return err
+		}
+
+		f, err = r.ReadRevision(rev.Hash, filename)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(os.Stderr, "%s at commit %s (%s)\n", args[0], rev.Hash[:7], rev.When.Format("2006-01-02 15:04"))
+	} else {
+		if !r.BranchExists(filename) {
+			return fmt.Errorf("the account does not exist")
+		}
+
+		_, err = r.VerifyBranch(filename, signers)
+		if err := untrusted(filename, err, c.insecure); err != nil {
+			return err
+		}
+
+		f, err = r.ReadFile(filename, filename)
+		if err != nil {
+			return err
+		}
 	}
 
-	f, err := utils.LoadFile(file)
+	p, err := decryptKey(f)
 	if err != nil {
 		return err
 	}
 
-	p := encrypt.NewPGP(pk, f, true)
+	p.Insecure = c.insecure
 
-	if err := p.LoadKeys(); err != nil {
+	if err := loadRecipients(r, p, args[0]); err != nil {
 		return err
 	}
 
-	if err := p.Keyring(3); err != nil {
-		return fmt.Errorf("[exit] only 3 passphrase attempts allowed")
-	}
-
 	if err := p.Decrypt(); err != nil {
 		return err
 	}
 
-	fmt.Println(string(p.Message))
+	if p.Author != nil {
+		fmt.Fprintf(os.Stderr, "%s was encrypted by %s\n", args[0], encrypt.Describe(p.Author))
+	} else {
+		fmt.Fprintf(os.Stderr, "warning: %s is not signed by a trusted recipient\n", args[0])
+	}
+
+	e := parseEntry(p.Message)
+
+	switch {
+	case c.field != "":
+		v, ok := e.field(c.field)
+		if !ok {
+			return fmt.Errorf("%s has no field named %s", args[0], c.field)
+		}
+		fmt.Println(v)
+	case c.line != 0:
+		l, ok := e.line(c.line)
+		if !ok {
+			return fmt.Errorf("%s has no line %d", args[0], c.line)
+		}
+		fmt.Println(l)
+	default:
+		fmt.Println(string(p.Message))
+	}
 
 	return nil
 }
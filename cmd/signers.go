@@ -0,0 +1,237 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/eiso/gpass/encrypt"
+	"github.com/eiso/gpass/git"
+	"github.com/eiso/gpass/utils"
+	"github.com/spf13/cobra"
+)
+
+// signersFile is the keyring on the gpass branch holding the public keys trusted to sign commits
+const signersFile = git.SignersFile
+
+type SignersCmd struct{}
+
+func NewSignersCmd() *SignersCmd {
+	return &SignersCmd{}
+}
+
+func (c *SignersCmd) Cmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "signers",
+		Short: "Manages the public keys that are trusted to sign changes to the store.",
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "Lists the trusted signers of the store.",
+		Args:  cobra.NoArgs,
+		RunE:  c.List,
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "add /path/to/public-key.asc...",
+		Short: "Adds armored public keys to the trusted signers of the store.",
+		Args:  cobra.MinimumNArgs(1),
+		RunE:  c.Add,
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "remove key-id|email",
+		Short: "Removes a trusted signer from the store.",
+		Args:  cobra.ExactArgs(1),
+		RunE:  c.Remove,
+	})
+
+	return cmd
+}
+
+func (c *SignersCmd) List(cmd *cobra.Command, args []string) error {
+	s, err := c.load()
+	if err != nil {
+		return err
+	}
+
+	if s.Len() == 0 {
+		fmt.Println("No signers have been added yet, add your own key with: gpass signers add")
+		return nil
+	}
+
+	for _, l := range s.List() {
+		fmt.Println(l)
+	}
+
+	return nil
+}
+
+func (c *SignersCmd) Add(cmd *cobra.Command, args []string) error {
+	s, err := c.load()
+	if err != nil {
+		return err
+	}
+
+	var added []string
+
+	for _, a := range args {
+		f, err := utils.LoadFile(a)
+		if err != nil {
+			return err
+		}
+
+		ids, err := s.Add(f)
+		if err != nil {
+			return fmt.Errorf("%s: %s", a, err)
+		}
+
+		added = append(added, ids...)
+	}
+
+	if len(added) == 0 {
+		fmt.Println("All keys are signers already")
+		return nil
+	}
+
+	if _, err := unlockKey(nil, false); err != nil {
+		return err
+	}
+
+	if err := c.save(s, fmt.Sprintf("Add signers: %v", added)); err != nil {
+		return err
+	}
+
+	for _, id := range added {
+		fmt.Println("Added signer", id)
+	}
+
+	return nil
+}
+
+func (c *SignersCmd) Remove(cmd *cobra.Command, args []string) error {
+	s, err := c.load()
+	if err != nil {
+		return err
+	}
+
+	d, err := s.Remove(args[0])
+	if err != nil {
+		return err
+	}
+
+	if s.Len() == 0 {
+		return fmt.Errorf("unable to remove the last signer of the store")
+	}
+
+	m := fmt.Sprintf("Are you sure you would like to remove %s?", d)
+	if !utils.ConfirmShellPrompt(m) {
+		return nil
+	}
+
+	if _, err := unlockKey(nil, false); err != nil {
+		return err
+	}
+
+	if err := c.save(s, fmt.Sprintf("Remove signer: %s", d)); err != nil {
+		return err
+	}
+
+	fmt.Println("Removed signer", d)
+
+	return nil
+}
+
+// load reads the signers, which are a keyring just like the recipients
+func (c *SignersCmd) load() (*encrypt.Recipients, error) {
+	if err := InitCheck(); err != nil {
+		return nil, err
+	}
+
+	r := Cfg.Repository
+
+	if err := r.Load(); err != nil {
+		return nil, err
+	}
+
+	if !r.BranchExists("gpass") {
+		return nil, notInitialized(r)
+	}
+
+	return readRecipients(r, signersFile)
+}
+
+func (c *SignersCmd) save(s *encrypt.Recipients, msg string) error {
+	b, err := s.Bytes()
+	if err != nil {
+		return err
+	}
+
+	return Cfg.Repository.WriteFile(Cfg.User, "gpass", signersFile, b, msg)
+}
+
+// readSigners returns the armored keyring of trusted signers on the gpass branch
+func readSigners(r *git.Repository) ([]byte, error) {
+	if !r.FileExists("gpass", signersFile) {
+		return nil, nil
+	}
+
+	return r.ReadFile("gpass", signersFile)
+}
+
+// errNoSigners is returned when changes have to be verified in a store without trusted signers
+var errNoSigners = fmt.Errorf("the store has no trusted signers yet, add your own key with: gpass signers add, or use --insecure to continue anyway")
+
+// trustedSigners returns the armored keyring of trusted signers, a store without signers, such as one
+// created by an older version of gpass, can only be read under --insecure until signers are added
+func trustedSigners(r *git.Repository, insecure bool) ([]byte, error) {
+	signers, err := readSigners(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(bytes.TrimSpace(signers)) == 0 && !insecure {
+		return nil, errNoSigners
+	}
+
+	return signers, nil
+}
+
+// untrusted turns a failed signature check of a branch into an error, or into a warning under --insecure
+func untrusted(branch string, err error, insecure bool) error {
+	if err == nil {
+		return nil
+	}
+
+	name := strings.TrimSuffix(branch, ".gpg")
+
+	if insecure {
+		fmt.Fprintf(os.Stderr, "warning: %s: %s\n", name, err)
+		return nil
+	}
+
+	return fmt.Errorf("%s: %s, use --insecure to continue anyway", name, err)
+}
+
+// fastForward updates the local branches from a remote, incoming changes that are not signed by a
+// trusted signer are refused or, under --insecure, only warned about
+func fastForward(r *git.Repository, remote string, insecure bool, action string) (*git.SyncResult, error) {
+	res, err := r.FastForward(remote, insecure)
+	if e, ok := err.(*git.UntrustedError); ok {
+		if signers, _ := readSigners(r); r.BranchExists("gpass") && len(bytes.TrimSpace(signers)) == 0 {
+			return nil, fmt.Errorf("refusing to %s %s, %s", action, remote, errNoSigners)
+		}
+		return nil, fmt.Errorf("refusing to %s %s, %s", action, remote, untrusted(e.Branch, e.Err, false))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range res.Untrusted {
+		untrusted(e.Branch, e.Err, true)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,284 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/eiso/gpass/encrypt"
+	"github.com/eiso/gpass/git"
+	"github.com/eiso/gpass/utils"
+	"github.com/spf13/cobra"
+)
+
+type SyncCmd struct {
+	remote   string
+	insecure bool
+}
+
+func NewSyncCmd() *SyncCmd {
+	return &SyncCmd{}
+}
+
+func (c *SyncCmd) Cmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Args:  cobra.NoArgs,
+		Short: "Pulls from and pushes to a remote, resolving diverged accounts.",
+		RunE:  c.Execute,
+	}
+
+	cmd.Flags().StringVarP(&c.remote, "remote", "r", "origin", "The git remote to synchronize with.")
+	cmd.Flags().BoolVar(&c.insecure, "insecure", false, "Only warn about incoming changes that are not signed by a trusted signer or recipient.")
+
+	return cmd
+}
+
+func (c *SyncCmd) Execute(cmd *cobra.Command, args []string) error {
+	if err := InitCheck(); err != nil {
+		return err
+	}
+
+	r := Cfg.Repository
+
+	if err := r.Load(); err != nil {
+		return err
+	}
+
+	if !r.BranchExists("gpass") {
+		return notInitialized(r)
+	}
+
+	if err := r.Fetch(c.remote); err != nil {
+		return err
+	}
+
+	res, err := fastForward(r, c.remote, c.insecure, "sync with")
+	if err != nil {
+		return err
+	}
+
+	for _, b := range res.Updated {
+		fmt.Println("Updated", b)
+	}
+
+	for _, b := range res.RemovedDiverged {
+		fmt.Printf("The removal of %s has diverged from %s and was not synchronized\n", strings.TrimSuffix(b, ".gpg"), c.remote)
+	}
+
+	// merges are committed, so the key is only unlocked when a branch has diverged
+	var p *encrypt.PGP
+	if len(res.Diverged) > 0 {
+		if p, err = unlockKey(nil, true); err != nil {
+			return err
+		}
+	}
+
+	var accounts []string
+
+	for _, b := range res.Diverged {
+		if b != "gpass" {
+			accounts = append(accounts, b)
+			continue
+		}
+
+		msg := fmt.Sprintf("Merge: gpass from %s", c.remote)
+		conflicts, err := r.MergeBranch(Cfg.User, c.remote, "gpass", msg, git.JournalFile)
+		if err != nil {
+			return err
+		}
+
+		if len(conflicts) > 0 {
+			fmt.Printf("gpass has diverged from %s, please merge %s with git\n", c.remote, strings.Join(conflicts, ", "))
+		}
+	}
+
+	if err := replayJournal(r); err != nil {
+		return err
+	}
+
+	if len(accounts) > 0 {
+		if err := c.resolve(r, p, accounts); err != nil {
+			return err
+		}
+	}
+
+	skipped, err := r.Push(c.remote)
+	if err != nil {
+		return err
+	}
+
+	for _, b := range skipped {
+		fmt.Printf("%s has diverged from %s and was not synchronized\n", b, c.remote)
+	}
+
+	fmt.Println("Successfully synchronized with", c.remote)
+
+	return nil
+}
+
+// resolve lets the user pick a side or edit the merge of every diverged account
+func (c *SyncCmd) resolve(r *git.Repository, p *encrypt.PGP, diverged []string) error {
+	for _, branch := range diverged {
+		if !r.BranchExists(branch) {
+			continue
+		}
+
+		if err := c.merge(r, p, branch); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// merge shows a three-way comparison of a diverged account and commits the chosen
+// result as a merge commit of the local and remote branch
+func (c *SyncCmd) merge(r *git.Repository, p *encrypt.PGP, filename string) error {
+	account := strings.TrimSuffix(filename, ".gpg")
+
+	d, err := r.Divergence(c.remote, filename)
+	if err != nil {
+		return err
+	}
+
+	if err := loadRecipients(r, p, account); err != nil {
+		return err
+	}
+	p.Insecure = c.insecure
+
+	var entries [3]*entry
+	var raw [3][]byte
+
+	for i, h := range []string{d.Base, d.Local, d.Remote} {
+		if h == "" {
+			continue
+		}
+
+		f, err := r.ReadRevision(h, filename)
+		if err != nil {
+			continue
+		}
+
+		p.Message = f
+		p.Encrypted = true
+
+		if err := p.Decrypt(); err != nil {
+			return fmt.Errorf("%s: %s", account, err)
+		}
+
+		raw[i] = p.Message
+		entries[i] = parseEntry(p.Message)
+	}
+
+	fields := mergeFields(entries[0], entries[1], entries[2])
+
+	fmt.Printf("\n%s has changed both locally and on %s:\n\n", account, c.remote)
+	printMerge(fields)
+
+	choices := []string{"l", "r", "e", "s"}
+	conflicts := false
+	for _, f := range fields {
+		conflicts = conflicts || f.conflict()
+	}
+
+	prompt := "Keep [l]ocal, [r]emote, [e]dit the merge or [s]kip"
+	if !conflicts {
+		prompt = "Keep [l]ocal, [r]emote, the [m]erged fields, [e]dit the merge or [s]kip"
+		choices = append(choices, "m")
+	}
+
+	var m []byte
+
+	switch utils.ChoiceShellPrompt(prompt, choices) {
+	case "l":
+		m = raw[1]
+	case "r":
+		m = raw[2]
+	case "m":
+		m = mergeEntry(entries[1], entries[2], fields)
+	case "e":
+		m, err = utils.EditorShellPrompt(mergeEntry(entries[1], entries[2], fields))
+		if err != nil {
+			return err
+		}
+	default:
+		return nil
+	}
+
+	if len(m) == 0 {
+		return fmt.Errorf("%s: the merged account is empty", account)
+	}
+
+	p.Message = m
+	p.Encrypted = false
+
+	if err := p.Encrypt(); err != nil {
+		return err
+	}
+
+	msg := fmt.Sprintf("Merge: %s from %s", account, c.remote)
+	if err := r.CommitMerge(Cfg.User, filename, filename, p.Message, msg, d.Local, d.Remote); err != nil {
+		return err
+	}
+
+	fmt.Println("Merged", account)
+
+	return nil
+}
+
+// replayJournal removes the accounts that were removed or moved on other clones
+func replayJournal(r *git.Repository) error {
+	res, err := r.Replay()
+	if err != nil {
+		return err
+	}
+
+	for _, e := range res.Applied {
+		account := strings.TrimSuffix(e.Branch, ".gpg")
+		if e.Op == "mv" {
+			fmt.Printf("Moved %s to %s\n", account, strings.TrimSuffix(e.To, ".gpg"))
+			continue
+		}
+		fmt.Println("Removed", account)
+	}
+
+	for _, e := range res.Conflicts {
+		fmt.Printf("%s changed after it was removed or moved on another clone and has been kept\n", strings.TrimSuffix(e.Branch, ".gpg"))
+	}
+
+	return nil
+}
+
+// printMerge prints the fields that differ between the base, local and remote revisions,
+// the password is masked and only shown as changed or unchanged
+func printMerge(fields []*fieldMerge) {
+	show := func(f *fieldMerge, s fieldState) string {
+		switch {
+		case !s.exists:
+			return "(none)"
+		case f.key == "password" && s == f.base:
+			return "******** (unchanged)"
+		case f.key == "password":
+			return "******** (changed)"
+		}
+		return strings.Replace(s.value, "\n", "\n            ", -1)
+	}
+
+	for _, f := range fields {
+		if f.local == f.remote && f.local == f.base {
+			continue
+		}
+
+		mark := ""
+		if f.conflict() {
+			mark = " (conflict)"
+		}
+
+		fmt.Printf("%s%s\n", f.key, mark)
+		fmt.Printf("  base:     %s\n", show(f, f.base))
+		fmt.Printf("  local:    %s\n", show(f, f.local))
+		fmt.Printf("  remote:   %s\n", show(f, f.remote))
+	}
+
+	fmt.Println()
+}
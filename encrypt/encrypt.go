@@ -2,11 +2,11 @@ package encrypt
 
 import (
 	"bytes"
+	"crypto/rsa"
 	"fmt"
 	"io/ioutil"
+	"math/big"
 	"os"
-	"path"
-	"syscall"
 
 	"golang.org/x/crypto/openpgp"
 	"golang.org/x/crypto/openpgp/armor"
@@ -19,6 +19,21 @@ type PGP struct {
 	PrivateKey []byte
 	Message    []byte
 	Encrypted  bool
+	// Recipients are the public keys messages are encrypted to, when empty only the private key is used.
+	// Decrypt trusts the signatures of the recipients and of the private key.
+	Recipients *Recipients
+	// Author is the trusted key that signed the last decrypted message, nil if it was not verified
+	Author *openpgp.Entity
+	// Insecure lets Decrypt accept messages that are not signed or are signed by an untrusted key
+	Insecure bool
+	// Agent decrypts the messages when set, the private key then only needs to be loaded and not unlocked
+	Agent Agent
+}
+
+// Agent decrypts messages on behalf of a process that has not unlocked the private key
+type Agent interface {
+	// Decrypt returns the message and the key id of its trusted signer, 0 if it was not verified
+	Decrypt(message []byte, recipients []byte, insecure bool) ([]byte, uint64, error)
 }
 
 var entityList openpgp.EntityList
@@ -58,84 +73,105 @@ func (f *PGP) LoadKeys() error {
 	return nil
 }
 
-// TODO: modify keyring and move this to utils
-func shellPrompt() []byte {
-	fmt.Print("Enter passphrase: ")
-	passphraseByte, err := terminal.ReadPassword(int(syscall.Stdin))
-	if err == nil {
-		fmt.Println("")
+// PublicKey returns the armored public key of the loaded private key
+func (f *PGP) PublicKey() ([]byte, error) {
+	if len(entityList) == 0 {
+		return nil, fmt.Errorf("The private key has not been loaded")
 	}
 
-	return passphraseByte
-}
+	var w bytes.Buffer
 
-// WriteFile writes the encrypted message to a new file, fails on existing files
-// TODO: modify and move to utils package
-func (f *PGP) WriteFile(repoPath string, filename string) error {
-	if len(f.Message) == 0 {
-		return fmt.Errorf("The message content has not been loaded")
+	a, err := armor.Encode(&w, openpgp.PublicKeyType, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to armor encode")
 	}
 
-	if !f.Encrypted {
-		return fmt.Errorf("Not allowed to write unencrypted content to a file")
+	if err := entityList[0].Serialize(a); err != nil {
+		return nil, fmt.Errorf("Unable to serialize the public key: %s", err)
 	}
 
-	p := path.Join(repoPath, filename)
+	if err := a.Close(); err != nil {
+		return nil, err
+	}
+
+	return w.Bytes(), nil
+}
+
+// ErrNoTerminal is returned when the passphrase can not be prompted for
+var ErrNoTerminal = fmt.Errorf("Unable to prompt for the passphrase without a terminal")
 
-	pd := path.Dir(p)
-	if pd != "" {
-		os.MkdirAll(pd, os.FileMode(0700))
+// TODO: modify keyring and move this to utils
+func shellPrompt() ([]byte, error) {
+	tty, err := terminalInput()
+	if err != nil {
+		return nil, err
 	}
 
-	o, err := os.Open(p)
-	if err == nil {
-		o.Close()
-		return fmt.Errorf("File already exists")
+	if tty != os.Stdin {
+		defer tty.Close()
 	}
-	o.Close()
 
-	d, err := os.Create(p)
+	fmt.Fprint(tty, "Enter passphrase: ")
+	passphraseByte, err := terminal.ReadPassword(int(tty.Fd()))
 	if err != nil {
-		return fmt.Errorf("Unable to create the file: %s", err)
+		return nil, err
 	}
+	fmt.Fprintln(tty, "")
 
-	if err := d.Chmod(os.FileMode(0600)); err != nil {
-		return fmt.Errorf("Unable to change permissions on file to 0600: %s", err)
+	return passphraseByte, nil
+}
+
+// terminalInput returns the terminal to read the passphrase from, which is not stdin when the
+// contents of an account are piped in
+func terminalInput() (*os.File, error) {
+	if terminal.IsTerminal(int(os.Stdin.Fd())) {
+		return os.Stdin, nil
 	}
 
-	if _, err := d.Write(f.Message); err != nil {
-		return fmt.Errorf("Unable to write to file: %s", err)
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, ErrNoTerminal
 	}
 
-	return nil
+	return tty, nil
 }
 
 //Keyring builds a pgp keyring based upon the users' private key
 func (f *PGP) Keyring(attempts int) error {
+	passphrase, err := shellPrompt()
+	if err != nil {
+		return err
+	}
+
+	err = f.Unlock(passphrase)
+
+	if err != nil && attempts > 1 {
+		fmt.Println("Sorry, try again.")
+		return f.Keyring(attempts - 1)
+	}
+
+	return err
+}
+
+// Unlock decrypts the loaded private key with a passphrase and adds it to the keyring
+func (f *PGP) Unlock(passphrase []byte) error {
 	entity := entityList[0]
 	success := false
 
-	passphraseByte := shellPrompt()
-
 	if entity.PrivateKey != nil && entity.PrivateKey.Encrypted {
-		err := entity.PrivateKey.Decrypt(passphraseByte)
+		err := entity.PrivateKey.Decrypt(passphrase)
 		if err == nil {
 			success = true
 		}
 	}
 
 	for _, subkey := range entity.Subkeys {
-		err := subkey.PrivateKey.Decrypt(passphraseByte)
+		err := subkey.PrivateKey.Decrypt(passphrase)
 		if err == nil {
 			success = true
 		}
 	}
 
-	if !success && attempts > 1 {
-		fmt.Println("Sorry, try again.")
-		return f.Keyring(attempts - 1)
-	}
-
 	if !success {
 		return fmt.Errorf("failed to decrypt private key")
 	}
@@ -145,12 +181,72 @@ func (f *PGP) Keyring(attempts int) error {
 	return nil
 }
 
+// Signer returns the unlocked private key to sign with
+func (f *PGP) Signer() (*openpgp.Entity, error) {
+	if len(entityList) == 0 {
+		return nil, fmt.Errorf("The private key has not been loaded")
+	}
+
+	e := entityList[0]
+	if e.PrivateKey == nil || e.PrivateKey.Encrypted {
+		return nil, fmt.Errorf("The private key has not been unlocked")
+	}
+
+	return e, nil
+}
+
+// Lock overwrites the unlocked private key with zeros, it has to be loaded and unlocked again before it can
+// be used. Copies the runtime made of it, for instance while growing a slice, can not be wiped.
+func (f *PGP) Lock() {
+	for _, e := range entityList {
+		wipe(e.PrivateKey)
+		for _, s := range e.Subkeys {
+			wipe(s.PrivateKey)
+		}
+	}
+
+	entityList = nil
+}
+
+func wipe(k *packet.PrivateKey) {
+	if k == nil {
+		return
+	}
+
+	if rk, ok := k.PrivateKey.(*rsa.PrivateKey); ok {
+		secrets := append([]*big.Int{rk.D, rk.Precomputed.Dp, rk.Precomputed.Dq, rk.Precomputed.Qinv}, rk.Primes...)
+		for _, v := range rk.Precomputed.CRTValues {
+			secrets = append(secrets, v.Exp, v.Coeff, v.R)
+		}
+
+		// SetInt64 only shortens the slice of words, the words themselves are overwritten first
+		for _, n := range secrets {
+			if n == nil {
+				continue
+			}
+			w := n.Bits()
+			for i := range w {
+				w[i] = 0
+			}
+			n.SetInt64(0)
+		}
+	}
+
+	// a wiped key must not be mistaken for an unlocked one
+	k.PrivateKey = nil
+	k.Encrypted = true
+}
+
 // Decrypt a message
 func (f *PGP) Decrypt() error {
 	if !f.Encrypted {
 		return fmt.Errorf("The message is not encrypted")
 	}
 
+	if f.Agent != nil {
+		return f.decryptWithAgent()
+	}
+
 	block, err := armor.Decode(bytes.NewReader([]byte(f.Message)))
 	if err != nil {
 		return fmt.Errorf("Invalid PGP message or not armor encoded: %s", err)
@@ -159,22 +255,143 @@ func (f *PGP) Decrypt() error {
 		return fmt.Errorf("This file is not a PGP message: %s", err)
 	}
 
-	md, err := openpgp.ReadMessage(block.Body, entityList, nil, nil)
+	keyring := append(openpgp.EntityList{}, entityList...)
+	if f.Recipients != nil {
+		keyring = append(keyring, f.Recipients.entities...)
+	}
+
+	md, err := openpgp.ReadMessage(block.Body, keyring, nil, nil)
 	if err != nil {
 		return fmt.Errorf("Unable to decrypt the message: %s", err)
 	}
 
+	// the signature is only checked once the whole body has been read
 	message, err := ioutil.ReadAll(md.UnverifiedBody)
 	if err != nil {
 		return fmt.Errorf("Unable to convert the decrypted message to a string: %s", err)
 	}
 
+	f.Author = nil
+
+	switch {
+	case md.SignatureError != nil:
+		return fmt.Errorf("The signature of the message is invalid: %s", md.SignatureError)
+	case md.SignedBy != nil:
+		f.Author = md.SignedBy.Entity
+	case f.Insecure:
+	case !md.IsSigned:
+		return fmt.Errorf("The message is not signed")
+	default:
+		return fmt.Errorf("The message is signed by the untrusted key %016X", md.SignedByKeyId)
+	}
+
 	f.Encrypted = false
 	f.Message = message
 
 	return nil
 }
 
+// decryptWithAgent decrypts the message through the agent and looks up its author among the trusted keys
+func (f *PGP) decryptWithAgent() error {
+	keyring := append(openpgp.EntityList{}, entityList...)
+
+	var rc []byte
+	if f.Recipients != nil && f.Recipients.Len() > 0 {
+		b, err := f.Recipients.Bytes()
+		if err != nil {
+			return err
+		}
+		rc = b
+		keyring = append(keyring, f.Recipients.entities...)
+	}
+
+	message, id, err := f.Agent.Decrypt(f.Message, rc, f.Insecure)
+	if err != nil {
+		return err
+	}
+
+	f.Author = nil
+	if id != 0 {
+		if keys := keyring.KeysById(id); len(keys) > 0 {
+			f.Author = keys[0].Entity
+		}
+	}
+
+	f.Encrypted = false
+	f.Message = message
+
+	return nil
+}
+
+// UpToDate returns true if the encrypted message is encrypted to exactly the keys Encrypt would use
+func (f *PGP) UpToDate() (bool, error) {
+	if !f.Encrypted {
+		return false, fmt.Errorf("The message is not encrypted")
+	}
+
+	block, err := armor.Decode(bytes.NewReader([]byte(f.Message)))
+	if err != nil {
+		return false, fmt.Errorf("Invalid PGP message or not armor encoded: %s", err)
+	}
+
+	var ids []uint64
+	r := packet.NewReader(block.Body)
+
+	for {
+		p, err := r.Next()
+		if err != nil {
+			return false, fmt.Errorf("Unable to read the message: %s", err)
+		}
+
+		k, ok := p.(*packet.EncryptedKey)
+		if !ok {
+			break
+		}
+		ids = append(ids, k.KeyId)
+	}
+
+	to := entityList
+	if f.Recipients != nil && f.Recipients.Len() > 0 {
+		to = f.Recipients.entities
+	}
+
+	return encryptedTo(to, ids), nil
+}
+
+// encryptedTo returns true if every entity holds one of the key ids and every key id belongs to an entity
+func encryptedTo(el openpgp.EntityList, ids []uint64) bool {
+	owner := func(id uint64) *openpgp.Entity {
+		for _, e := range el {
+			if e.PrimaryKey.KeyId == id {
+				return e
+			}
+			for _, s := range e.Subkeys {
+				if s.PublicKey.KeyId == id {
+					return e
+				}
+			}
+		}
+		return nil
+	}
+
+	used := make(map[*openpgp.Entity]bool)
+	for _, id := range ids {
+		e := owner(id)
+		if e == nil {
+			return false
+		}
+		used[e] = true
+	}
+
+	for _, e := range el {
+		if !used[e] {
+			return false
+		}
+	}
+
+	return true
+}
+
 // Encrypt a message
 func (f *PGP) Encrypt() error {
 	if f.Encrypted {
@@ -188,9 +405,17 @@ func (f *PGP) Encrypt() error {
 		return fmt.Errorf("Unable to armor encode")
 	}
 
-	//entity := entityList[0]
+	to := entityList
+	if f.Recipients != nil && f.Recipients.Len() > 0 {
+		to = f.Recipients.entities
+	}
+
+	signer, err := f.Signer()
+	if err != nil {
+		return fmt.Errorf("Unable to sign the message: %s", err)
+	}
 
-	e, err := openpgp.Encrypt(b, entityList, nil, nil, nil)
+	e, err := openpgp.Encrypt(b, to, signer, nil, nil)
 	if err != nil {
 		return fmt.Errorf("Unable to load keyring for encryption: %s", err)
 	}
@@ -0,0 +1,67 @@
+package encrypt
+
+import (
+	"crypto/rsa"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTerminalInput(t *testing.T) {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	defer r.Close()
+
+	// the contents of an account piped into insert --multiline have consumed stdin
+	w.Write([]byte("hunter2\n"))
+	w.Close()
+
+	stdin := os.Stdin
+	os.Stdin = r
+	defer func() { os.Stdin = stdin }()
+
+	tty, err := terminalInput()
+	if err != nil {
+		require.Equal(t, ErrNoTerminal, err)
+		return
+	}
+	defer tty.Close()
+
+	require.NotEqual(t, r, tty)
+	require.Equal(t, "/dev/tty", tty.Name())
+}
+
+func TestLock(t *testing.T) {
+	k, err := GenerateKey("John Doe", "john@doe.org", []byte("passphrase"))
+	require.NoError(t, err)
+
+	p := NewPGP(k, nil, false)
+	require.NoError(t, p.LoadKeys())
+	require.NoError(t, p.Unlock([]byte("passphrase")))
+
+	e, err := p.Signer()
+	require.NoError(t, err)
+
+	var words [][]big.Word
+	for _, pk := range []interface{}{e.PrivateKey.PrivateKey, e.Subkeys[0].PrivateKey.PrivateKey} {
+		rk := pk.(*rsa.PrivateKey)
+		for _, n := range append([]*big.Int{rk.D, rk.Precomputed.Dp, rk.Precomputed.Dq}, rk.Primes...) {
+			words = append(words, n.Bits())
+		}
+	}
+
+	p.Lock()
+
+	// the memory that held the key is overwritten, not just released
+	for _, w := range words {
+		require.NotEmpty(t, w)
+		for _, v := range w {
+			require.Zero(t, v)
+		}
+	}
+
+	_, err = p.Signer()
+	require.Error(t, err)
+}
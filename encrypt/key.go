@@ -0,0 +1,213 @@
+package encrypt
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha1"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/openpgp/packet"
+	"golang.org/x/crypto/openpgp/s2k"
+)
+
+const (
+	// packet tags of secret keys, see RFC 4880 section 5.5.1
+	tagSecretKey    = 5
+	tagSecretSubkey = 7
+
+	// s2kSHA1 marks a secret key as encrypted with a SHA-1 checksum, see RFC 4880 section 5.5.3
+	s2kSHA1 = 254
+	// cipherAES256 is the OpenPGP identifier of AES-256, see RFC 4880 section 9.2
+	cipherAES256 = 9
+	// algoRSA is the OpenPGP identifier of RSA public keys, see RFC 4880 section 9.1
+	algoRSA = 1
+)
+
+// GenerateKey creates a new RSA 4096 key pair and returns the armored private key encrypted with the passphrase
+func GenerateKey(name string, email string, passphrase []byte) ([]byte, error) {
+	if len(passphrase) == 0 {
+		return nil, fmt.Errorf("A passphrase is required to protect the private key")
+	}
+
+	c := &packet.Config{
+		RSABits:     4096,
+		DefaultHash: crypto.SHA256,
+	}
+
+	e, err := openpgp.NewEntity(name, "", email, c)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to generate a new key pair: %s", err)
+	}
+
+	// x/crypto/openpgp only serializes unencrypted private keys, the secret key
+	// packets are encrypted with the passphrase after serializing the entity
+	var plain bytes.Buffer
+	if err := e.SerializePrivate(&plain, c); err != nil {
+		return nil, fmt.Errorf("Unable to serialize the private key: %s", err)
+	}
+
+	var w bytes.Buffer
+
+	a, err := armor.Encode(&w, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to armor encode")
+	}
+
+	if err := encryptSecretKeys(a, plain.Bytes(), passphrase); err != nil {
+		return nil, err
+	}
+
+	if err := a.Close(); err != nil {
+		return nil, err
+	}
+
+	return w.Bytes(), nil
+}
+
+// encryptSecretKeys copies a serialized entity to w, encrypting all secret key packets with the passphrase
+func encryptSecretKeys(w io.Writer, b []byte, passphrase []byte) error {
+	for len(b) > 0 {
+		tag, body, rest, err := readPacket(b)
+		if err != nil {
+			return err
+		}
+		b = rest
+
+		if tag == tagSecretKey || tag == tagSecretSubkey {
+			body, err = encryptSecretKey(body, passphrase)
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := writePacket(w, tag, body); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// encryptSecretKey encrypts the body of an unencrypted RSA secret key packet, see RFC 4880 section 5.5.3
+func encryptSecretKey(body []byte, passphrase []byte) ([]byte, error) {
+	// version, creation time and algorithm followed by the public MPIs n and e
+	n := 6
+	if len(body) < n || body[0] != 4 || body[5] != algoRSA {
+		return nil, fmt.Errorf("Only version 4 RSA secret keys can be encrypted")
+	}
+
+	for i := 0; i < 2; i++ {
+		l, err := mpiLength(body[n:])
+		if err != nil {
+			return nil, err
+		}
+		n += l
+	}
+
+	if len(body) < n+3 || body[n] != 0 {
+		return nil, fmt.Errorf("The secret key is already encrypted")
+	}
+
+	// strip the string-to-key usage and the two octet checksum of the unencrypted key
+	secret := body[n+1 : len(body)-2]
+
+	var out bytes.Buffer
+	out.Write(body[:n])
+	out.WriteByte(s2kSHA1)
+	out.WriteByte(cipherAES256)
+
+	key := make([]byte, 32)
+	sc := &s2k.Config{Hash: crypto.SHA256, S2KCount: 65011712}
+	if err := s2k.Serialize(&out, key, rand.Reader, passphrase, sc); err != nil {
+		return nil, fmt.Errorf("Unable to derive a key from the passphrase: %s", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	iv := make([]byte, block.BlockSize())
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		return nil, err
+	}
+	out.Write(iv)
+
+	sum := sha1.Sum(secret)
+	data := append(append([]byte{}, secret...), sum[:]...)
+
+	cipher.NewCFBEncrypter(block, iv).XORKeyStream(data, data)
+	out.Write(data)
+
+	return out.Bytes(), nil
+}
+
+// mpiLength returns the encoded length of the multiprecision integer at the start of b
+func mpiLength(b []byte) (int, error) {
+	if len(b) < 2 {
+		return 0, fmt.Errorf("Truncated key material")
+	}
+
+	l := 2 + ((int(b[0])<<8|int(b[1]))+7)/8
+	if len(b) < l {
+		return 0, fmt.Errorf("Truncated key material")
+	}
+
+	return l, nil
+}
+
+// readPacket splits the first new format packet off b, see RFC 4880 section 4.2.2
+func readPacket(b []byte) (tag byte, body []byte, rest []byte, err error) {
+	if len(b) < 2 || b[0]&0xc0 != 0xc0 {
+		return 0, nil, nil, fmt.Errorf("Unsupported packet format")
+	}
+
+	tag = b[0] & 0x3f
+	var l, h int
+
+	switch {
+	case b[1] < 192:
+		l, h = int(b[1]), 2
+	case b[1] < 224 && len(b) >= 3:
+		l, h = (int(b[1])-192)<<8+int(b[2])+192, 3
+	case b[1] == 255 && len(b) >= 6:
+		l, h = int(b[2])<<24|int(b[3])<<16|int(b[4])<<8|int(b[5]), 6
+	default:
+		return 0, nil, nil, fmt.Errorf("Unsupported packet length")
+	}
+
+	if len(b) < h+l {
+		return 0, nil, nil, fmt.Errorf("Truncated packet")
+	}
+
+	return tag, b[h : h+l], b[h+l:], nil
+}
+
+// writePacket writes a packet with a new format header, see RFC 4880 section 4.2.2
+func writePacket(w io.Writer, tag byte, body []byte) error {
+	l := len(body)
+	h := []byte{0xc0 | tag}
+
+	switch {
+	case l < 192:
+		h = append(h, byte(l))
+	case l < 8384:
+		l -= 192
+		h = append(h, byte(192+(l>>8)), byte(l))
+	default:
+		h = append(h, 255, byte(l>>24), byte(l>>16), byte(l>>8), byte(l))
+	}
+
+	if _, err := w.Write(h); err != nil {
+		return err
+	}
+
+	_, err := w.Write(body)
+	return err
+}
@@ -0,0 +1,49 @@
+package encrypt
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+func TestGenerateKey(t *testing.T) {
+	k, err := GenerateKey("John Doe", "john@doe.org", []byte("passphrase"))
+	require.NoError(t, err)
+
+	block, err := armor.Decode(bytes.NewReader(k))
+	require.NoError(t, err)
+	require.Equal(t, openpgp.PrivateKeyType, block.Type)
+
+	e, err := openpgp.ReadEntity(packet.NewReader(block.Body))
+	require.NoError(t, err)
+	require.True(t, e.PrivateKey.Encrypted)
+	require.Len(t, e.Subkeys, 1)
+	require.True(t, e.Subkeys[0].PrivateKey.Encrypted)
+	require.Contains(t, e.Identities, "John Doe <john@doe.org>")
+
+	require.Error(t, e.PrivateKey.Decrypt([]byte("wrong")))
+	require.NoError(t, e.PrivateKey.Decrypt([]byte("passphrase")))
+	require.NoError(t, e.Subkeys[0].PrivateKey.Decrypt([]byte("passphrase")))
+
+	var w bytes.Buffer
+	m, err := openpgp.Encrypt(&w, openpgp.EntityList{e}, nil, nil, nil)
+	require.NoError(t, err)
+	_, err = m.Write([]byte("hunter2"))
+	require.NoError(t, err)
+	require.NoError(t, m.Close())
+
+	md, err := openpgp.ReadMessage(&w, openpgp.EntityList{e}, nil, nil)
+	require.NoError(t, err)
+
+	b, err := ioutil.ReadAll(md.UnverifiedBody)
+	require.NoError(t, err)
+	require.Equal(t, "hunter2", string(b))
+
+	_, err = GenerateKey("John Doe", "john@doe.org", nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,161 @@
+package encrypt
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+)
+
+// Recipients holds the public keys a message is encrypted to
+type Recipients struct {
+	entities openpgp.EntityList
+}
+
+// NewRecipients parses an armored keyring of public keys, an empty keyring has no recipients
+func NewRecipients(b []byte) (*Recipients, error) {
+	r := new(Recipients)
+
+	if len(bytes.TrimSpace(b)) == 0 {
+		return r, nil
+	}
+
+	el, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read the recipients: %s", err)
+	}
+
+	r.entities = el
+
+	return r, nil
+}
+
+// Add adds the armored public keys in b and returns the ids of the keys that were added
+func (r *Recipients) Add(b []byte) ([]string, error) {
+	el, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("Not an armor encoded PGP public key: %s", err)
+	}
+
+	var added []string
+
+	for _, e := range el {
+		if r.find(e.PrimaryKey.KeyId) != nil {
+			continue
+		}
+
+		r.entities = append(r.entities, e)
+		added = append(added, KeyID(e))
+	}
+
+	return added, nil
+}
+
+// Remove removes the single recipient matching a key id, fingerprint or part of its identity
+func (r *Recipients) Remove(s string) (string, error) {
+	var matches []int
+
+	for i, e := range r.entities {
+		if matchEntity(e, s) {
+			matches = append(matches, i)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no recipient matches %s", s)
+	case 1:
+	default:
+		return "", fmt.Errorf("%d recipients match %s, please use a key id", len(matches), s)
+	}
+
+	i := matches[0]
+	d := Describe(r.entities[i])
+	r.entities = append(r.entities[:i], r.entities[i+1:]...)
+
+	return d, nil
+}
+
+// List returns a description of every recipient
+func (r *Recipients) List() []string {
+	var l []string
+
+	for _, e := range r.entities {
+		l = append(l, Describe(e))
+	}
+
+	return l
+}
+
+// Len returns the number of recipients
+func (r *Recipients) Len() int {
+	return len(r.entities)
+}
+
+// Bytes returns the recipients as an armored keyring of public keys
+func (r *Recipients) Bytes() ([]byte, error) {
+	var w bytes.Buffer
+
+	a, err := armor.Encode(&w, openpgp.PublicKeyType, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to armor encode")
+	}
+
+	for _, e := range r.entities {
+		if err := e.Serialize(a); err != nil {
+			return nil, fmt.Errorf("Unable to serialize the public key %s: %s", KeyID(e), err)
+		}
+	}
+
+	if err := a.Close(); err != nil {
+		return nil, err
+	}
+
+	return w.Bytes(), nil
+}
+
+func (r *Recipients) find(id uint64) *openpgp.Entity {
+	for _, e := range r.entities {
+		if e.PrimaryKey.KeyId == id {
+			return e
+		}
+	}
+
+	return nil
+}
+
+// KeyID returns the long key id of an entity as hex
+func KeyID(e *openpgp.Entity) string {
+	return fmt.Sprintf("%016X", e.PrimaryKey.KeyId)
+}
+
+// Describe returns the long key id and the identities of an entity
+func Describe(e *openpgp.Entity) string {
+	var ids []string
+	for id := range e.Identities {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	return fmt.Sprintf("%s %s", KeyID(e), strings.Join(ids, ", "))
+}
+
+func matchEntity(e *openpgp.Entity, s string) bool {
+	s = strings.ToUpper(strings.TrimPrefix(s, "0x"))
+	fp := fmt.Sprintf("%X", e.PrimaryKey.Fingerprint)
+
+	if len(s) >= 8 && (strings.HasSuffix(KeyID(e), s) || fp == s) {
+		return true
+	}
+
+	for id := range e.Identities {
+		if strings.Contains(strings.ToUpper(id), s) {
+			return true
+		}
+	}
+
+	return false
+}
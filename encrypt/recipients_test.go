@@ -0,0 +1,148 @@
+package encrypt
+
+import (
+	"bytes"
+	"crypto"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+func newPublicKey(t *testing.T, name string, email string) []byte {
+	e, err := openpgp.NewEntity(name, "", email, &packet.Config{RSABits: 1024, DefaultHash: crypto.SHA256})
+	require.NoError(t, err)
+
+	var w bytes.Buffer
+	a, err := armor.Encode(&w, openpgp.PublicKeyType, nil)
+	require.NoError(t, err)
+	require.NoError(t, e.Serialize(a))
+	require.NoError(t, a.Close())
+
+	return w.Bytes()
+}
+
+func TestRecipients(t *testing.T) {
+	r, err := NewRecipients(nil)
+	require.NoError(t, err)
+	require.Equal(t, 0, r.Len())
+
+	john := newPublicKey(t, "John Doe", "john@doe.org")
+	jane := newPublicKey(t, "Jane Doe", "jane@doe.org")
+
+	added, err := r.Add(john)
+	require.NoError(t, err)
+	require.Len(t, added, 1)
+	johnID := added[0]
+
+	added, err = r.Add(john)
+	require.NoError(t, err)
+	require.Empty(t, added)
+
+	_, err = r.Add(jane)
+	require.NoError(t, err)
+
+	b, err := r.Bytes()
+	require.NoError(t, err)
+
+	r, err = NewRecipients(b)
+	require.NoError(t, err)
+	require.Equal(t, 2, r.Len())
+
+	_, err = r.Remove("doe.org")
+	require.Error(t, err)
+
+	d, err := r.Remove("jane@doe.org")
+	require.NoError(t, err)
+	require.Contains(t, d, "Jane Doe <jane@doe.org>")
+	require.Equal(t, 1, r.Len())
+
+	d, err = r.Remove(johnID[8:])
+	require.NoError(t, err)
+	require.Contains(t, d, johnID)
+	require.Equal(t, 0, r.Len())
+
+	_, err = r.Add([]byte("not a key"))
+	require.Error(t, err)
+}
+
+func TestUpToDate(t *testing.T) {
+	c := &packet.Config{RSABits: 1024, DefaultHash: crypto.SHA256}
+
+	john, err := openpgp.NewEntity("John Doe", "", "john@doe.org", c)
+	require.NoError(t, err)
+	jane, err := openpgp.NewEntity("Jane Doe", "", "jane@doe.org", c)
+	require.NoError(t, err)
+
+	entityList = openpgp.EntityList{john}
+	defer func() { entityList = nil }()
+
+	p := NewPGP(nil, []byte("hunter2"), false)
+	p.Recipients = &Recipients{entities: openpgp.EntityList{john}}
+	require.NoError(t, p.Encrypt())
+
+	ok, err := p.UpToDate()
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	p.Recipients = &Recipients{entities: openpgp.EntityList{john, jane}}
+	ok, err = p.UpToDate()
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	p.Recipients = &Recipients{entities: openpgp.EntityList{jane}}
+	ok, err = p.UpToDate()
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestSignedMessage(t *testing.T) {
+	c := &packet.Config{RSABits: 1024, DefaultHash: crypto.SHA256}
+
+	john, err := openpgp.NewEntity("John Doe", "", "john@doe.org", c)
+	require.NoError(t, err)
+	jane, err := openpgp.NewEntity("Jane Doe", "", "jane@doe.org", c)
+	require.NoError(t, err)
+
+	defer func() { entityList = nil }()
+
+	// jane encrypts to john and signs with her own key
+	entityList = openpgp.EntityList{jane}
+
+	p := NewPGP(nil, []byte("hunter2"), false)
+	p.Recipients = &Recipients{entities: openpgp.EntityList{john}}
+	require.NoError(t, p.Encrypt())
+	signed := p.Message
+
+	// john only trusts jane once she is a recipient
+	entityList = openpgp.EntityList{john}
+
+	p = NewPGP(nil, signed, true)
+	require.Error(t, p.Decrypt())
+
+	p.Insecure = true
+	require.NoError(t, p.Decrypt())
+	require.Nil(t, p.Author)
+	require.Equal(t, "hunter2", string(p.Message))
+
+	p = NewPGP(nil, signed, true)
+	p.Recipients = &Recipients{entities: openpgp.EntityList{john, jane}}
+	require.NoError(t, p.Decrypt())
+	require.Equal(t, jane.PrimaryKey.KeyId, p.Author.PrimaryKey.KeyId)
+
+	// an unsigned message is refused
+	var w bytes.Buffer
+	a, err := armor.Encode(&w, "PGP MESSAGE", nil)
+	require.NoError(t, err)
+	m, err := openpgp.Encrypt(a, openpgp.EntityList{john}, nil, nil, nil)
+	require.NoError(t, err)
+	_, err = m.Write([]byte("hunter2"))
+	require.NoError(t, err)
+	require.NoError(t, m.Close())
+	require.NoError(t, a.Close())
+
+	p = NewPGP(nil, w.Bytes(), true)
+	require.Error(t, p.Decrypt())
+}
@@ -0,0 +1,167 @@
+package generate
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+const (
+	lower   = "abcdefghijklmnopqrstuvwxyz"
+	upper   = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	digits  = "0123456789"
+	symbols = "!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+
+	// ambiguous characters are easily confused with each other when read or typed
+	ambiguous = "0O1lI|`'\""
+
+	// maxAttempts limits how often a password is regenerated to satisfy the required classes
+	maxAttempts = 1000
+)
+
+// Classes are the character classes a password can be required to contain
+var Classes = []string{"lower", "upper", "digit", "symbol"}
+
+// Policy describes which characters a generated password is built from
+type Policy struct {
+	// Length is the number of characters in the password
+	Length int
+	// NoSymbols excludes symbols from the password
+	NoSymbols bool
+	// NoAmbiguous excludes characters that are easily confused such as 0 and O
+	NoAmbiguous bool
+	// Required lists the classes that must appear at least once in the password
+	Required []string
+}
+
+// Password generates a password from a crypto-secure source according to the policy
+func Password(p *Policy) (string, error) {
+	if p.Length < 1 {
+		return "", fmt.Errorf("the password length must be at least 1")
+	}
+
+	if len(p.Required) > p.Length {
+		return "", fmt.Errorf("a password of length %d cannot contain %d required classes", p.Length, len(p.Required))
+	}
+
+	var required []string
+	for _, c := range p.Required {
+		s, err := p.class(c)
+		if err != nil {
+			return "", err
+		}
+		required = append(required, s)
+	}
+
+	var all string
+	for _, c := range Classes {
+		if c == "symbol" && p.NoSymbols {
+			continue
+		}
+
+		s, err := p.class(c)
+		if err != nil {
+			return "", err
+		}
+		all += s
+	}
+
+	for i := 0; i < maxAttempts; i++ {
+		pass, err := randomString(all, p.Length)
+		if err != nil {
+			return "", err
+		}
+
+		if containsAll(pass, required) {
+			return pass, nil
+		}
+	}
+
+	return "", fmt.Errorf("unable to generate a password that satisfies the required classes")
+}
+
+// Passphrase generates a diceware-style passphrase of n words from the bundled wordlist
+func Passphrase(n int, separator string) (string, error) {
+	if n < 1 {
+		return "", fmt.Errorf("the passphrase must contain at least 1 word")
+	}
+
+	words := make([]string, n)
+	for i := range words {
+		j, err := randomInt(len(wordlist))
+		if err != nil {
+			return "", err
+		}
+		words[i] = wordlist[j]
+	}
+
+	return strings.Join(words, separator), nil
+}
+
+// class returns the characters of a class allowed by the policy
+func (p *Policy) class(name string) (string, error) {
+	var s string
+
+	switch name {
+	case "lower":
+		s = lower
+	case "upper":
+		s = upper
+	case "digit":
+		s = digits
+	case "symbol":
+		if p.NoSymbols {
+			return "", fmt.Errorf("symbols cannot be both excluded and required")
+		}
+		s = symbols
+	default:
+		return "", fmt.Errorf("unknown character class %s, valid classes are: %s", name, strings.Join(Classes, ", "))
+	}
+
+	if p.NoAmbiguous {
+		s = strings.Map(func(r rune) rune {
+			if strings.ContainsRune(ambiguous, r) {
+				return -1
+			}
+			return r
+		}, s)
+	}
+
+	return s, nil
+}
+
+// randomString picks n characters uniformly from charset
+func randomString(charset string, n int) (string, error) {
+	b := make([]byte, n)
+	for i := range b {
+		j, err := randomInt(len(charset))
+		if err != nil {
+			return "", err
+		}
+		b[i] = charset[j]
+	}
+
+	return string(b), nil
+}
+
+// randomInt returns a uniform random integer in [0, max) from crypto/rand
+func randomInt(max int) (int, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(max)))
+	if err != nil {
+		return 0, fmt.Errorf("Unable to read from the random source: %s", err)
+	}
+
+	return int(n.Int64()), nil
+}
+
+// containsAll returns true if s contains at least one character of every set
+func containsAll(s string, sets []string) bool {
+	for _, set := range sets {
+		if !strings.ContainsAny(s, set) {
+			return false
+		}
+	}
+
+	return true
+}
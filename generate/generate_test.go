@@ -0,0 +1,58 @@
+package generate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPassword(t *testing.T) {
+	p := &Policy{Length: 32, NoSymbols: true, NoAmbiguous: true, Required: []string{"digit", "upper"}}
+
+	for i := 0; i < 100; i++ {
+		pass, err := Password(p)
+		require.NoError(t, err)
+		require.Len(t, pass, 32)
+		require.False(t, strings.ContainsAny(pass, symbols))
+		require.False(t, strings.ContainsAny(pass, ambiguous))
+		require.True(t, strings.ContainsAny(pass, digits))
+		require.True(t, strings.ContainsAny(pass, upper))
+	}
+}
+
+func TestPasswordInvalidPolicy(t *testing.T) {
+	_, err := Password(&Policy{Length: 0})
+	require.Error(t, err)
+
+	_, err = Password(&Policy{Length: 8, NoSymbols: true, Required: []string{"symbol"}})
+	require.Error(t, err)
+
+	_, err = Password(&Policy{Length: 8, Required: []string{"emoji"}})
+	require.Error(t, err)
+
+	_, err = Password(&Policy{Length: 1, Required: []string{"lower", "upper"}})
+	require.Error(t, err)
+}
+
+func TestPassphrase(t *testing.T) {
+	pass, err := Passphrase(6, "-")
+	require.NoError(t, err)
+
+	words := strings.Split(pass, "-")
+	require.Len(t, words, 6)
+
+	_, err = Passphrase(0, "-")
+	require.Error(t, err)
+}
+
+func TestWordlist(t *testing.T) {
+	require.Len(t, wordlist, 7776)
+
+	seen := map[string]bool{}
+	for _, w := range wordlist {
+		require.False(t, seen[w], "duplicate word %s", w)
+		require.NotContains(t, w, "-")
+		seen[w] = true
+	}
+}
@@ -5,9 +5,12 @@ import (
 	"fmt"
 	"io/ioutil"
 	"path"
+	"strconv"
+	"strings"
 	"time"
 
 	homedir "github.com/mitchellh/go-homedir"
+	"golang.org/x/crypto/openpgp"
 	git "gopkg.in/src-d/go-git.v4"
 	"gopkg.in/src-d/go-git.v4/plumbing"
 	"gopkg.in/src-d/go-git.v4/plumbing/format/config"
@@ -21,6 +24,20 @@ type Repository struct {
 	root *git.Repository
 }
 
+// Revision is a single commit in the history of a branch
+type Revision struct {
+	// Hash is the full hex hash of the commit
+	Hash string
+	// Author is the name of the commit author
+	Author string
+	// Email is the email of the commit author
+	Email string
+	// When is the time the commit was authored
+	When time.Time
+	// Message is the commit message
+	Message string
+}
+
 // User is the relevant user information
 type User struct {
 	// Name is the name in Git's global .config
@@ -29,6 +46,8 @@ type User struct {
 	Email string
 	// HomeFolder is the system's home folder
 	HomeFolder string
+	// SignKey is the unlocked private key commits are signed with, it is never stored
+	SignKey *openpgp.Entity `json:"-"`
 }
 
 // Init populates the User type with information parsed from the system
@@ -89,7 +108,7 @@ func (u *User) load() error {
 	return nil
 }
 
-// Load a git repository from disk
+// Load a git repository from disk, either with a work tree or bare
 func (r *Repository) Load() error {
 	s, err := git.PlainOpen(r.Path)
 	if err != nil {
@@ -100,193 +119,230 @@ func (r *Repository) Load() error {
 	return nil
 }
 
-// CreateBranch creates a new branch based on an existing branch or returns an error
-func (r *Repository) CreateBranch(origin string, new string) error {
-	origin = fmt.Sprintf("refs/heads/%s", origin)
-	new = fmt.Sprintf("refs/heads/%s", new)
+// Create initializes a new git repository on disk or returns an error
+func (r *Repository) Create() error {
+	return r.create(false)
+}
+
+// CreateBare initializes a new bare git repository on disk or returns an error
+func (r *Repository) CreateBare() error {
+	return r.create(true)
+}
 
-	w, err := r.root.Worktree()
+func (r *Repository) create(bare bool) error {
+	s, err := git.PlainInit(r.Path, bare)
 	if err != nil {
-		return fmt.Errorf("Unable to load the work tree: %s", err)
+		return fmt.Errorf("Unable to create a repository at %s: %s", r.Path, err)
 	}
 
-	ref, err := r.root.Reference(plumbing.ReferenceName(origin), false)
+	r.root = s
+	return nil
+}
+
+// IsBare returns true if the repository has no work tree
+func (r *Repository) IsBare() bool {
+	_, err := r.root.Worktree()
+	return err == git.ErrIsBareRepository
+}
+
+// CreateBranch creates a new branch based on an existing branch or returns an error
+func (r *Repository) CreateBranch(origin string, new string) error {
+	ref, err := r.root.Reference(branchRef(origin), false)
 	if err != nil {
 		return err
 	}
 
-	o := &git.CheckoutOptions{}
-	o.Branch = plumbing.ReferenceName(new)
-	o.Create = true
-	o.Hash = ref.Hash()
+	if _, err := r.root.Reference(branchRef(new), false); err == nil {
+		return fmt.Errorf("Unable to create a new branch: %s already exists", new)
+	}
 
-	if err = w.Checkout(o); err != nil {
+	if err := r.root.Storer.SetReference(plumbing.NewHashReference(branchRef(new), ref.Hash())); err != nil {
 		return fmt.Errorf("Unable to create a new branch: %s", err)
 	}
 
 	return nil
 }
 
-// CreateOrphanBranch creates an orphan branch or returns an error
+// CreateOrphanBranch creates an orphan branch with an empty initial commit or returns an error
 func (r *Repository) CreateOrphanBranch(u *User, s string) error {
-	name := fmt.Sprintf("refs/heads/%s", s)
-
-	w, err := r.root.Worktree()
-	if err != nil {
-		return fmt.Errorf("Unable to load the work tree: %s", err)
-	}
-
-	o := &git.CheckoutOptions{}
-	o.Branch = plumbing.ReferenceName(name)
-	o.Create = true
-
-	if err = w.Checkout(o); err != nil {
-		return fmt.Errorf("Unable to create a new branch: %s", err)
+	if _, err := r.root.Reference(branchRef(s), false); err == nil {
+		return fmt.Errorf("Unable to create a new branch: %s already exists", s)
 	}
 
-	var h []plumbing.Hash
-
 	msg := fmt.Sprintf("creating branch for: %s", s)
-	_, err = w.Commit(msg, &git.CommitOptions{
-		Author: &object.Signature{
-			Name:  u.Name,
-			Email: u.Email,
-			When:  time.Now(),
-		},
-		Parents: h,
-	})
-	if err != nil {
+	if err := r.commitTree(u, s, nil, nil, msg); err != nil {
 		return fmt.Errorf("Unable to make the initial commit: %s", err)
 	}
 
 	return nil
 }
 
-// CheckoutBranch switches to an existing branch or returns an error
-func (r *Repository) CheckoutBranch(s string) error {
-	name := fmt.Sprintf("refs/heads/%s", s)
+// AddTagBranch marks a branch as removed by adding a tombstone for it or returns an error
+func (r *Repository) AddTagBranch(tag string, s string) error {
+	ref, err := r.root.Reference(branchRef(s), false)
+	if err != nil {
+		return err
+	}
 
-	w, err := r.root.Worktree()
+	commit, err := r.root.CommitObject(ref.Hash())
 	if err != nil {
-		return fmt.Errorf("Unable to load the work tree: %s", err)
+		return err
 	}
 
-	o := &git.CheckoutOptions{}
-	o.Branch = plumbing.ReferenceName(name)
-	o.Create = false
+	tr := plumbing.NewHashReference(removedRef(tag), commit.Hash)
 
-	if err = w.Checkout(o); err != nil {
-		return fmt.Errorf("Unable to checkout branch: %s", err)
+	if err := r.root.Storer.SetReference(tr); err != nil {
+		return err
 	}
 
 	return nil
 }
 
-// AddTagBranch adds a tag to a branch or returns an error
-func (r *Repository) AddTagBranch(tag string, s string) error {
-	name := fmt.Sprintf("refs/heads/%s", s)
-
-	ref, err := r.root.Reference(plumbing.ReferenceName(name), false)
+// TagBranch recreates a branch from its tombstone or returns an error
+func (r *Repository) TagBranch(s string) error {
+	ref, err := r.root.Reference(removedRef(s), false)
 	if err != nil {
 		return err
 	}
 
-	commit, err := r.root.CommitObject(ref.Hash())
-	if err != nil {
-		return err
+	if err := r.root.Storer.SetReference(plumbing.NewHashReference(branchRef(s), ref.Hash())); err != nil {
+		return fmt.Errorf("Unable to create a new branch: %s", err)
 	}
 
-	tr := plumbing.NewHashReference(plumbing.ReferenceName(tag), commit.Hash)
+	return nil
+}
 
-	if err := r.root.Storer.SetReference(tr); err != nil {
-		return err
+// ReadFile reads a file from the tip of a branch without checking it out or returns an error
+func (r *Repository) ReadFile(branch string, filename string) ([]byte, error) {
+	ref, err := r.root.Reference(branchRef(branch), false)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	return r.readFile(ref.Hash(), filename)
 }
 
-// TagBranch creates/switches to a branch based on a tag name or returns an error
-func (r *Repository) TagBranch(s string, create bool) error {
-	tag := fmt.Sprintf("refs/tags/%s", s)
-	name := fmt.Sprintf("refs/heads/%s", s)
+// ReadRevision reads a file as it was at a commit or returns an error
+func (r *Repository) ReadRevision(hash string, filename string) ([]byte, error) {
+	return r.readFile(plumbing.NewHash(hash), filename)
+}
 
-	ref, err := r.root.Reference(plumbing.ReferenceName(tag), false)
+func (r *Repository) readFile(hash plumbing.Hash, filename string) ([]byte, error) {
+	commit, err := r.root.CommitObject(hash)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	w, err := r.root.Worktree()
+	f, err := commit.File(filename)
 	if err != nil {
-		return fmt.Errorf("Unable to load the work tree: %s", err)
+		return nil, fmt.Errorf("Unable to find %s in commit %s: %s", filename, hash, err)
 	}
 
-	o := &git.CheckoutOptions{}
+	c, err := f.Contents()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read %s: %s", filename, err)
+	}
 
-	o.Hash = ref.Hash()
-	o.Branch = plumbing.ReferenceName(name)
-	o.Create = create
+	return []byte(c), nil
+}
 
-	if err = w.Checkout(o); err != nil {
-		return fmt.Errorf("Unable to create a new branch: %s", err)
+// FileExists returns true if a file exists at the tip of a branch or false if it doesn't
+func (r *Repository) FileExists(branch string, filename string) bool {
+	ref, err := r.root.Reference(branchRef(branch), false)
+	if err != nil {
+		return false
 	}
 
-	return nil
-}
+	commit, err := r.root.CommitObject(ref.Hash())
+	if err != nil {
+		return false
+	}
 
-// CommitFile adds the file & commits it or returns an error
-func (r *Repository) CommitFile(u *User, filename string, msg string) error {
+	_, err = commit.File(filename)
 
-	w, err := r.root.Worktree()
+	return err == nil
+}
+
+// History returns the commits of a branch, or of its tombstone if the branch does not exist, newest first
+func (r *Repository) History(s string) ([]*Revision, error) {
+	ref, err := r.root.Reference(branchRef(s), false)
+	if err != nil {
+		ref, err = r.root.Reference(removedRef(s), false)
+	}
 	if err != nil {
-		return fmt.Errorf("Unable to load the work tree: %s", err)
+		return nil, fmt.Errorf("Unable to find a branch or tag named %s", s)
 	}
 
-	_, err = w.Add(filename)
+	iter, err := r.root.Log(&git.LogOptions{From: ref.Hash()})
 	if err != nil {
-		return fmt.Errorf("Unable to git add the file: %s", err)
+		return nil, fmt.Errorf("Unable to read the history of %s: %s", s, err)
 	}
 
-	_, err = w.Commit(msg, &git.CommitOptions{
-		Author: &object.Signature{
-			Name:  u.Name,
-			Email: u.Email,
-			When:  time.Now(),
-		},
-		All: true,
-	})
+	var h []*Revision
 
+	err = iter.ForEach(func(c *object.Commit) error {
+		h = append(h, &Revision{
+			Hash:    c.Hash.String(),
+			Author:  c.Author.Name,
+			Email:   c.Author.Email,
+			When:    c.Author.When,
+			Message: c.Message,
+		})
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("Unable to commit: %s", err)
+		return nil, err
 	}
 
-	return nil
+	return h, nil
 }
 
-// Commit makes a commit or returns an error
-func (r *Repository) Commit(u *User, filename string, msg string) error {
-	w, err := r.root.Worktree()
+// Revision resolves a revision in the history of a branch or tag: the number of commits before
+// the tip, a (prefix of a) commit hash or a date, returning the last commit made at or before it
+func (r *Repository) Revision(s string, rev string) (*Revision, error) {
+	h, err := r.History(s)
 	if err != nil {
-		return fmt.Errorf("Unable to load the work tree: %s", err)
+		return nil, err
 	}
 
-	_, err = w.Commit(msg, &git.CommitOptions{
-		Author: &object.Signature{
-			Name:  u.Name,
-			Email: u.Email,
-			When:  time.Now(),
-		},
-		All: true,
-	})
+	if n, err := strconv.Atoi(rev); err == nil && len(rev) < 7 {
+		if n < 0 || n >= len(h) {
+			return nil, fmt.Errorf("%s only has %d revisions", s, len(h))
+		}
+		return h[n], nil
+	}
 
+	if isHex(rev) && len(rev) >= 4 {
+		var found *Revision
+		for _, c := range h {
+			if !strings.HasPrefix(c.Hash, strings.ToLower(rev)) {
+				continue
+			}
+			if found != nil {
+				return nil, fmt.Errorf("%s is ambiguous, please use a longer hash", rev)
+			}
+			found = c
+		}
+		if found != nil {
+			return found, nil
+		}
+	}
+
+	t, err := parseDate(rev)
 	if err != nil {
-		return fmt.Errorf("Unable to commit: %s", err)
+		return nil, fmt.Errorf("%s is not a revision number, commit hash or date of %s", rev, s)
 	}
 
-	return nil
+	for _, c := range h {
+		if !c.When.After(t) {
+			return c, nil
+		}
+	}
 
+	return nil, fmt.Errorf("%s did not exist yet at %s", s, rev)
 }
 
-// ListBranches returns a list of all branches in the repository
+// ListBranches returns a list of all regular branches in the repository
 func (r *Repository) ListBranches() []string {
 	var b []string
 
@@ -306,23 +362,13 @@ func (r *Repository) ListBranches() []string {
 
 // BranchExists returns true if a branch exists based on its name or false if it doesn't
 func (r *Repository) BranchExists(n string) bool {
-	b := false
-	refs, _ := r.root.References()
-	refs.ForEach(func(ref *plumbing.Reference) error {
-		if ref.Type() == plumbing.HashReference {
-			if ref.Name().IsBranch() && ref.Name().Short() == n {
-				b = true
-			}
-		}
-		return nil
-	})
-
-	return b
+	_, err := r.root.Reference(branchRef(n), false)
+	return err == nil
 }
 
 // RemoveBranch deletes a branch based on its name or returns an error
 func (r *Repository) RemoveBranch(n string) error {
-	err := r.root.Storer.RemoveReference(plumbing.ReferenceName("refs/heads/" + n))
+	err := r.root.Storer.RemoveReference(branchRef(n))
 	if err != nil {
 		return err
 	}
@@ -330,18 +376,48 @@ func (r *Repository) RemoveBranch(n string) error {
 	return nil
 }
 
-// TagExists returns true if a tag reference exists or false if it doesn't
+// TagExists returns true if a branch has a tombstone or false if it doesn't
 func (r *Repository) TagExists(n string) bool {
-	b := false
-	refs, _ := r.root.References()
-	refs.ForEach(func(ref *plumbing.Reference) error {
-		if ref.Type() == plumbing.HashReference {
-			if ref.Name().IsTag() && ref.Name().Short() == n {
-				b = true
-			}
+	_, err := r.root.Reference(removedRef(n), false)
+	return err == nil
+}
+
+// RemoveTag deletes the tombstone of a branch or returns an error
+func (r *Repository) RemoveTag(n string) error {
+	err := r.root.Storer.RemoveReference(removedRef(n))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func isHex(s string) bool {
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
 		}
-		return nil
-	})
+	}
 
-	return b
+	return s != ""
+}
+
+// parseDate parses a timestamp in the local time zone, a date without a time means the end of that day
+func parseDate(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02T15:04"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+
+	t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return t.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
 }
@@ -5,13 +5,15 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	"golang.org/x/crypto/openpgp/packet"
 	git "gopkg.in/src-d/go-git.v4"
 	"gopkg.in/src-d/go-git.v4/plumbing"
 	"gopkg.in/src-d/go-git.v4/plumbing/object"
@@ -36,31 +38,20 @@ func (s *GitSuite) newUser() *User {
 		HomeFolder: "/home/john-doe/"}
 }
 
-// newTestRepository creates a git repository using the systems `git` used for testing 
+// newTestRepository creates a git repository used for testing
 func (s *GitSuite) newTestRepository(name string) *Repository {
 	dir, err := ioutil.TempDir("", name)
 	require.NoError(s.T(), err)
 
-	dotgit := filepath.Join(dir, ".git")
-
-	gitExec(s, dotgit, dir, "init")
-	
 	repo := &Repository{Path: dir}
-	
+
 	// create a master branch with a single file and a single commit
-	err = repo.Load()
+	err = repo.Create()
 	require.NoError(s.T(), err)
 
 	w, err := repo.root.Worktree()
 	require.NoError(s.T(), err)
 
-	o := &git.CheckoutOptions{}
-	o.Branch = plumbing.ReferenceName("refs/heads/master")
-	o.Create = true
-
-	err = w.Checkout(o)
-	require.NoError(s.T(), err)
-
 	err = ioutil.WriteFile(filepath.Join(repo.Path, "empty"), []byte(""), 0600)
 	require.NoError(s.T(), err)
 
@@ -81,7 +72,7 @@ func (s *GitSuite) newTestRepository(name string) *Repository {
 	w, err = repo.root.Worktree()
 	require.NoError(s.T(), err)
 
-	o = &git.CheckoutOptions{}
+	o := &git.CheckoutOptions{}
 	o.Branch = plumbing.ReferenceName("refs/heads/test")
 	o.Create = true
 
@@ -114,6 +105,63 @@ func (s *GitSuite) newTestRepository(name string) *Repository {
 	return repo
 }
 
+func (s *GitSuite) TestCreate() {
+	dir, err := ioutil.TempDir("", "gpass-test")
+	require.NoError(s.T(), err)
+	defer os.RemoveAll(dir)
+
+	gpass := &Repository{Path: filepath.Join(dir, "store")}
+
+	err = gpass.Create()
+	require.NoError(s.T(), err)
+
+	err = gpass.CreateOrphanBranch(s.user, "gpass")
+	require.NoError(s.T(), err)
+
+	s.True(gpass.BranchExists("gpass"))
+
+	err = gpass.Create()
+	require.Error(s.T(), err)
+
+	err = gpass.Load()
+	require.NoError(s.T(), err)
+	s.False(gpass.IsBare())
+}
+
+func (s *GitSuite) TestCreateBare() {
+	dir, err := ioutil.TempDir("", "gpass-test")
+	require.NoError(s.T(), err)
+	defer os.RemoveAll(dir)
+
+	gpass := &Repository{Path: filepath.Join(dir, "store.git")}
+
+	err = gpass.CreateBare()
+	require.NoError(s.T(), err)
+	s.True(gpass.IsBare())
+
+	err = gpass.CreateOrphanBranch(s.user, "gpass")
+	require.NoError(s.T(), err)
+	err = gpass.WriteFile(s.user, "web/a.gpg", "web/a.gpg", []byte("a"), "add")
+	require.NoError(s.T(), err)
+	err = gpass.RecordMove(s.user, "a.gpg", "web/a.gpg")
+	require.NoError(s.T(), err)
+
+	bare := &Repository{Path: gpass.Path}
+	err = bare.Load()
+	require.NoError(s.T(), err)
+	s.True(bare.IsBare())
+	s.True(bare.BranchExists("gpass"))
+	s.Equal([]string{"web/a.gpg"}, bare.ListAccounts())
+
+	f, err := bare.ReadFile("web/a.gpg", "web/a.gpg")
+	require.NoError(s.T(), err)
+	s.Equal("a", string(f))
+
+	entries, err := bare.Journal()
+	require.NoError(s.T(), err)
+	s.Len(entries, 1)
+}
+
 func (s *GitSuite) TestCreateBranch() {
 	gpass := s.newTestRepository("gpass-test")
 	defer os.RemoveAll(gpass.Path)
@@ -130,7 +178,10 @@ func (s *GitSuite) TestCreateBranch() {
 
 	gpassRef, err := gpass.root.Head()
 
-	s.Equal(string(gpassRef.Name()), ref)
+	s.Equal("refs/heads/master", string(gpassRef.Name()))
+
+	_, err = gpass.root.Reference(plumbing.ReferenceName(ref), false)
+	require.NoError(s.T(), err)
 }
 
 func (s *GitSuite) TestCreateOrphanBranch() {
@@ -150,44 +201,718 @@ func (s *GitSuite) TestCreateOrphanBranch() {
 	require.NoError(s.T(), err)
 }
 
-func (s *GitSuite) TestCheckoutBranch() {
+func (s *GitSuite) TestReadFile() {
 	gpass := s.newTestRepository("gpass-test")
 	defer os.RemoveAll(gpass.Path)
 
-	n := "test"
-	ref := fmt.Sprintf("refs/heads/%s", n)
+	err := gpass.Load()
+	require.NoError(s.T(), err)
+
+	f, err := gpass.ReadFile("test", "empty2")
+	require.NoError(s.T(), err)
+	s.Empty(f)
+
+	_, err = gpass.ReadFile("master", "empty2")
+	require.Error(s.T(), err)
+
+	headRef, err := gpass.root.Head()
+	require.NoError(s.T(), err)
+
+	s.Equal("refs/heads/master", string(headRef.Name()))
+}
+
+func (s *GitSuite) TestFileExists() {
+	gpass := s.newTestRepository("gpass-test")
+	defer os.RemoveAll(gpass.Path)
 
 	err := gpass.Load()
 	require.NoError(s.T(), err)
 
-	err = gpass.CheckoutBranch(n)
+	s.True(gpass.FileExists("test", "empty2"))
+	s.False(gpass.FileExists("master", "empty2"))
+	s.False(gpass.FileExists("missing", "empty"))
+}
+
+func (s *GitSuite) TestHistory() {
+	gpass := s.newTestRepository("gpass-test")
+	defer os.RemoveAll(gpass.Path)
+
+	err := gpass.Load()
 	require.NoError(s.T(), err)
 
-	gpassRef, err := gpass.root.Reference(plumbing.ReferenceName(ref), false)
+	h, err := gpass.History("test")
 	require.NoError(s.T(), err)
+	require.Len(s.T(), h, 2)
 
-	headRef, err := gpass.root.Head()
+	s.Equal("add empty2", h[0].Message)
+	s.Equal("add empty", h[1].Message)
+	s.Equal(s.user.Name, h[0].Author)
+	s.Equal(s.user.Email, h[0].Email)
+
+	err = gpass.AddTagBranch("test", "test")
+	require.NoError(s.T(), err)
+
+	err = gpass.RemoveBranch("test")
+	require.NoError(s.T(), err)
+
+	tagged, err := gpass.History("test")
+	require.NoError(s.T(), err)
+	s.Equal(h, tagged)
+
+	_, err = gpass.History("missing")
+	require.Error(s.T(), err)
+}
+
+func (s *GitSuite) TestRevision() {
+	gpass := s.newTestRepository("gpass-test")
+	defer os.RemoveAll(gpass.Path)
+
+	err := gpass.Load()
+	require.NoError(s.T(), err)
+
+	h, err := gpass.History("test")
+	require.NoError(s.T(), err)
+
+	rev, err := gpass.Revision("test", "1")
+	require.NoError(s.T(), err)
+	s.Equal(h[1], rev)
+
+	_, err = gpass.Revision("test", "2")
+	require.Error(s.T(), err)
+
+	rev, err = gpass.Revision("test", h[1].Hash[:8])
+	require.NoError(s.T(), err)
+	s.Equal(h[1], rev)
+
+	rev, err = gpass.Revision("test", h[0].When.Format(time.RFC3339))
+	require.NoError(s.T(), err)
+	s.Equal(h[0], rev)
+
+	rev, err = gpass.Revision("test", time.Now().Format("2006-01-02"))
+	require.NoError(s.T(), err)
+	s.Equal(h[0], rev)
+
+	_, err = gpass.Revision("test", "2001-01-01")
+	require.Error(s.T(), err)
+
+	f, err := gpass.ReadRevision(h[0].Hash, "empty2")
+	require.NoError(s.T(), err)
+	s.Empty(f)
+
+	_, err = gpass.ReadRevision(h[1].Hash, "empty2")
+	require.Error(s.T(), err)
+}
+
+func (s *GitSuite) TestRemoveTag() {
+	gpass := s.newTestRepository("gpass-test")
+	defer os.RemoveAll(gpass.Path)
+
+	err := gpass.Load()
+	require.NoError(s.T(), err)
+
+	err = gpass.AddTagBranch("test", "test")
+	require.NoError(s.T(), err)
+	s.True(gpass.TagExists("test"))
+
+	err = gpass.RemoveTag("test")
+	require.NoError(s.T(), err)
+	s.False(gpass.TagExists("test"))
+}
+
+func (s *GitSuite) commitTestFile(r *Repository, branch string, filename string, content string) {
+	err := r.WriteFile(s.user, branch, filename, []byte(content), "update "+filename)
+	require.NoError(s.T(), err)
+}
+
+func (s *GitSuite) TestSync() {
+	local := s.newTestRepository("gpass-test")
+	defer os.RemoveAll(local.Path)
+
+	dir, err := ioutil.TempDir("", "gpass-test")
+	require.NoError(s.T(), err)
+	defer os.RemoveAll(dir)
+
+	_, err = git.PlainInit(filepath.Join(dir, "remote.git"), true)
+	require.NoError(s.T(), err)
+	url := "file://" + filepath.Join(dir, "remote.git")
+
+	err = local.CreateBranch("master", "gpass")
+	require.NoError(s.T(), err)
+	err = local.CreateBranch("master", "web/account.gpg")
+	require.NoError(s.T(), err)
+	err = local.AddRemote("origin", url)
+	require.NoError(s.T(), err)
+	err = local.Fetch("origin")
+	require.NoError(s.T(), err)
+
+	skipped, err := local.Push("origin")
+	require.NoError(s.T(), err)
+	s.Empty(skipped)
+
+	clone := &Repository{Path: filepath.Join(dir, "clone")}
+	err = clone.Create()
+	require.NoError(s.T(), err)
+	err = clone.AddRemote("origin", url)
+	require.NoError(s.T(), err)
+	err = clone.Fetch("origin")
+	require.NoError(s.T(), err)
+
+	res, err := clone.FastForward("origin", true)
+	require.NoError(s.T(), err)
+	s.ElementsMatch([]string{"gpass", "web/account.gpg"}, res.Updated)
+	s.True(clone.BranchExists("web/account.gpg"))
+	s.False(clone.BranchExists("test"))
+
+	// a fast-forward on one side is picked up by the other
+	s.commitTestFile(local, "web/account.gpg", "account", "local")
+	_, err = local.Push("origin")
+	require.NoError(s.T(), err)
+
+	err = clone.Fetch("origin")
+	require.NoError(s.T(), err)
+	res, err = clone.FastForward("origin", true)
+	require.NoError(s.T(), err)
+	s.Equal([]string{"web/account.gpg"}, res.Updated)
+
+	// changes on both sides diverge
+	s.commitTestFile(local, "web/account.gpg", "account", "local 2")
+	_, err = local.Push("origin")
+	require.NoError(s.T(), err)
+	s.commitTestFile(clone, "web/account.gpg", "account", "clone")
+
+	err = clone.Fetch("origin")
+	require.NoError(s.T(), err)
+
+	in, err := clone.Incoming("origin")
+	require.NoError(s.T(), err)
+	tip, err := local.root.Reference(branchRef("web/account.gpg"), false)
+	require.NoError(s.T(), err)
+	s.Equal(map[string]string{"web/account.gpg": tip.Hash().String()}, in)
+
+	res, err = clone.FastForward("origin", true)
+	require.NoError(s.T(), err)
+	s.Empty(res.Updated)
+	s.Equal([]string{"web/account.gpg"}, res.Diverged)
+
+	skipped, err = clone.Push("origin")
+	require.NoError(s.T(), err)
+	s.Equal([]string{"web/account.gpg"}, skipped)
+
+	// a merge commit with both parents resolves the divergence
+	d, err := clone.Divergence("origin", "web/account.gpg")
+	require.NoError(s.T(), err)
+	s.NotEmpty(d.Base)
+	s.NotEqual(d.Local, d.Remote)
+
+	base, err := clone.ReadRevision(d.Base, "account")
+	require.NoError(s.T(), err)
+	s.Equal("local", string(base))
+
+	err = clone.CommitMerge(s.user, "web/account.gpg", "account", []byte("merged"), "merge", d.Local, d.Remote)
+	require.NoError(s.T(), err)
+
+	skipped, err = clone.Push("origin")
+	require.NoError(s.T(), err)
+	s.Empty(skipped)
+
+	err = local.Fetch("origin")
+	require.NoError(s.T(), err)
+	res, err = local.FastForward("origin", true)
+	require.NoError(s.T(), err)
+	s.Equal([]string{"web/account.gpg"}, res.Updated)
+
+	f, err := local.ReadFile("web/account.gpg", "account")
+	require.NoError(s.T(), err)
+	s.Equal("merged", string(f))
+}
+
+func (s *GitSuite) TestJournal() {
+	local := s.newTestRepository("gpass-test")
+	defer os.RemoveAll(local.Path)
+
+	dir, err := ioutil.TempDir("", "gpass-test")
+	require.NoError(s.T(), err)
+	defer os.RemoveAll(dir)
+
+	_, err = git.PlainInit(filepath.Join(dir, "remote.git"), true)
+	require.NoError(s.T(), err)
+	url := "file://" + filepath.Join(dir, "remote.git")
+
+	err = local.CreateBranch("master", "gpass")
+	require.NoError(s.T(), err)
+	err = local.CreateBranch("master", "old.gpg")
+	require.NoError(s.T(), err)
+	s.commitTestFile(local, "old.gpg", "old", "old")
+	err = local.CreateBranch("master", "removed.gpg")
+	require.NoError(s.T(), err)
+	s.commitTestFile(local, "removed.gpg", "removed", "removed")
+
+	err = local.AddRemote("origin", url)
+	require.NoError(s.T(), err)
+	err = local.Fetch("origin")
+	require.NoError(s.T(), err)
+	_, err = local.Push("origin")
+	require.NoError(s.T(), err)
+
+	clone := &Repository{Path: filepath.Join(dir, "clone")}
+	err = clone.Create()
+	require.NoError(s.T(), err)
+	err = clone.AddRemote("origin", url)
+	require.NoError(s.T(), err)
+	err = clone.Fetch("origin")
+	require.NoError(s.T(), err)
+	_, err = clone.FastForward("origin", true)
+	require.NoError(s.T(), err)
+	s.True(clone.BranchExists("removed.gpg"))
+
+	// remove and move accounts the way the rm and mv commands do
+	err = local.RemoveFile(s.user, "removed.gpg", "removed", "remove")
+	require.NoError(s.T(), err)
+	err = local.AddTagBranch("removed.gpg", "removed.gpg")
+	require.NoError(s.T(), err)
+	err = local.RemoveBranch("removed.gpg")
+	require.NoError(s.T(), err)
+	err = local.RecordRemove(s.user, "removed.gpg")
 	require.NoError(s.T(), err)
 
-	s.Equal(gpassRef, headRef)	
+	err = local.CreateBranch("old.gpg", "new.gpg")
+	require.NoError(s.T(), err)
+	err = local.RemoveBranch("old.gpg")
+	require.NoError(s.T(), err)
+	s.commitTestFile(local, "new.gpg", "old", "moved")
+	err = local.RecordMove(s.user, "old.gpg", "new.gpg")
+	require.NoError(s.T(), err)
+
+	entries, err := local.Journal()
+	require.NoError(s.T(), err)
+	require.Len(s.T(), entries, 2)
+	s.Equal("rm", entries[0].Op)
+	s.Equal("mv", entries[1].Op)
+	s.Equal("new.gpg", entries[1].To)
+
+	// the removed branches are not revived and are deleted from the remote
+	err = local.Fetch("origin")
+	require.NoError(s.T(), err)
+	res, err := local.FastForward("origin", true)
+	require.NoError(s.T(), err)
+	s.Empty(res.Updated)
+	_, err = local.Push("origin")
+	require.NoError(s.T(), err)
+
+	// the clone replays the journal
+	err = clone.Fetch("origin")
+	require.NoError(s.T(), err)
+	_, err = clone.FastForward("origin", true)
+	require.NoError(s.T(), err)
+
+	rep, err := clone.Replay()
+	require.NoError(s.T(), err)
+	s.Len(rep.Applied, 2)
+	s.Empty(rep.Conflicts)
+	s.False(clone.BranchExists("removed.gpg"))
+	s.False(clone.BranchExists("old.gpg"))
+	s.True(clone.BranchExists("new.gpg"))
+	s.True(clone.TagExists("removed.gpg"))
+
+	// a removed account that is inserted again is left alone
+	err = local.TagBranch("removed.gpg")
+	require.NoError(s.T(), err)
+	s.commitTestFile(local, "removed.gpg", "removed", "again")
+	rep, err = local.Replay()
+	require.NoError(s.T(), err)
+	s.Empty(rep.Applied)
+	s.True(local.BranchExists("removed.gpg"))
+
+	// and is brought back on the clone, the tombstone it descends from stays behind
+	_, err = local.Push("origin")
+	require.NoError(s.T(), err)
+	err = clone.Fetch("origin")
+	require.NoError(s.T(), err)
+	res, err = clone.FastForward("origin", true)
+	require.NoError(s.T(), err)
+	s.Contains(res.Updated, "removed.gpg")
+
+	rep, err = clone.Replay()
+	require.NoError(s.T(), err)
+	s.Empty(rep.Applied)
+	s.True(clone.BranchExists("removed.gpg"))
+	s.True(clone.TagExists("removed.gpg"))
+
+	// removing it again on the clone moves its tombstone, which a fetch does not overwrite
+	old, err := clone.root.Reference(removedRef("removed.gpg"), false)
+	require.NoError(s.T(), err)
+	err = clone.RemoveFile(s.user, "removed.gpg", "removed", "remove on the clone")
+	require.NoError(s.T(), err)
+	err = clone.AddTagBranch("removed.gpg", "removed.gpg")
+	require.NoError(s.T(), err)
+	err = clone.RemoveBranch("removed.gpg")
+	require.NoError(s.T(), err)
+	tomb, err := clone.root.Reference(removedRef("removed.gpg"), false)
+	require.NoError(s.T(), err)
+	s.NotEqual(old.Hash(), tomb.Hash())
+
+	err = clone.Fetch("origin")
+	require.NoError(s.T(), err)
+	ref, err := clone.root.Reference(removedRef("removed.gpg"), false)
+	require.NoError(s.T(), err)
+	s.Equal(tomb.Hash(), ref.Hash())
+
+	skipped, err := clone.Push("origin")
+	require.NoError(s.T(), err)
+	s.Empty(skipped)
+
+	// a tombstone that moved on both sides is reported and left untouched on either side
+	err = local.RemoveFile(s.user, "removed.gpg", "removed", "remove on local")
+	require.NoError(s.T(), err)
+	err = local.AddTagBranch("removed.gpg", "removed.gpg")
+	require.NoError(s.T(), err)
+	mine, err := local.root.Reference(removedRef("removed.gpg"), false)
+	require.NoError(s.T(), err)
+
+	err = local.Fetch("origin")
+	require.NoError(s.T(), err)
+	res, err = local.FastForward("origin", true)
+	require.NoError(s.T(), err)
+	s.Equal([]string{"removed.gpg"}, res.RemovedDiverged)
+	ref, err = local.root.Reference(removedRef("removed.gpg"), false)
+	require.NoError(s.T(), err)
+	s.Equal(mine.Hash(), ref.Hash())
+
+	skipped, err = local.Push("origin")
+	require.NoError(s.T(), err)
+	s.Contains(skipped, "removed/removed.gpg")
+
+	err = clone.Fetch("origin")
+	require.NoError(s.T(), err)
+	ref, err = clone.root.Reference(trackingRemovedRef("origin", "removed.gpg"), false)
+	require.NoError(s.T(), err)
+	s.Equal(tomb.Hash(), ref.Hash())
 }
 
+func (s *GitSuite) TestMergeBranch() {
+	a := "rm\t" + plumbing.ZeroHash.String() + "\ta.gpg\n"
+	b := "rm\t" + plumbing.ZeroHash.String() + "\tb.gpg\n"
+	c := "rm\t" + plumbing.ZeroHash.String() + "\tc.gpg\n"
+
+	local := s.newTestRepository("gpass-test")
+	defer os.RemoveAll(local.Path)
+
+	dir, err := ioutil.TempDir("", "gpass-test")
+	require.NoError(s.T(), err)
+	defer os.RemoveAll(dir)
+
+	_, err = git.PlainInit(filepath.Join(dir, "remote.git"), true)
+	require.NoError(s.T(), err)
+	url := "file://" + filepath.Join(dir, "remote.git")
 
-// TODO: should be removed once creating git repositories with go-git is added to this package
-// creates an unnecessary dependency for `git` to exist on the system
-func gitExec(s *GitSuite, dir string, worktree string, command ...string) {
-	cmd := exec.Command("git",
-		append([]string{"--git-dir", dir, "--work-tree", worktree},
-			command...)...)
+	err = local.CreateBranch("master", "gpass")
+	require.NoError(s.T(), err)
+	s.commitTestFile(local, "gpass", JournalFile, a)
+	err = local.AddRemote("origin", url)
+	require.NoError(s.T(), err)
+	err = local.Fetch("origin")
+	require.NoError(s.T(), err)
+	_, err = local.Push("origin")
+	require.NoError(s.T(), err)
 
-	var out bytes.Buffer
-	var stderr bytes.Buffer
-	cmd.Stdout = &out
-	cmd.Stderr = &stderr
+	clone := &Repository{Path: filepath.Join(dir, "clone")}
+	err = clone.Create()
+	require.NoError(s.T(), err)
+	err = clone.AddRemote("origin", url)
+	require.NoError(s.T(), err)
+	err = clone.Fetch("origin")
+	require.NoError(s.T(), err)
+	_, err = clone.FastForward("origin", true)
+	require.NoError(s.T(), err)
+
+	s.commitTestFile(local, "gpass", JournalFile, a+b)
+	s.commitTestFile(local, "gpass", "recipients", "local")
+	_, err = local.Push("origin")
+	require.NoError(s.T(), err)
+
+	s.commitTestFile(clone, "gpass", JournalFile, a+c)
+	err = clone.Fetch("origin")
+	require.NoError(s.T(), err)
+	res, err := clone.FastForward("origin", true)
+	require.NoError(s.T(), err)
+	s.Equal([]string{"gpass"}, res.Diverged)
+
+	conflicts, err := clone.MergeBranch(s.user, "origin", "gpass", "merge")
+	require.NoError(s.T(), err)
+	s.Equal([]string{JournalFile}, conflicts)
+
+	conflicts, err = clone.MergeBranch(s.user, "origin", "gpass", "merge", JournalFile)
+	require.NoError(s.T(), err)
+	s.Empty(conflicts)
 
-	err := cmd.Run()
-	if err != nil {
-		err = fmt.Errorf(fmt.Sprint(err) + ": " + stderr.String())
+	f, err := clone.ReadFile("gpass", JournalFile)
+	require.NoError(s.T(), err)
+	s.Equal(a+c+b, string(f))
+
+	f, err = clone.ReadFile("gpass", "recipients")
+	require.NoError(s.T(), err)
+	s.Equal("local", string(f))
+
+	skipped, err := clone.Push("origin")
+	require.NoError(s.T(), err)
+	s.Empty(skipped)
+}
+
+func (s *GitSuite) TestMigrate() {
+	gpass := s.newTestRepository("gpass-test")
+	defer os.RemoveAll(gpass.Path)
+
+	head, err := gpass.root.Head()
+	require.NoError(s.T(), err)
+
+	for _, n := range []string{"refs/heads/gpass", "refs/heads/web/a.gpg", "refs/tags/b.gpg", "refs/remotes/origin/web/a.gpg"} {
+		err = gpass.root.Storer.SetReference(plumbing.NewHashReference(plumbing.ReferenceName(n), head.Hash()))
+		require.NoError(s.T(), err)
 	}
+	err = gpass.root.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, "refs/heads/web/a.gpg"))
+	require.NoError(s.T(), err)
+
+	err = gpass.AddRemote("origin", "file:///nonexistent")
+	require.NoError(s.T(), err)
+
+	s.True(gpass.Legacy())
+	s.False(gpass.BranchExists("gpass"))
+
+	res, err := gpass.Migrate()
+	require.NoError(s.T(), err)
+	s.Equal(4, res.Moved)
+	s.Equal(head.Hash().String(), res.Detached)
+
+	s.False(gpass.Legacy())
+	s.True(gpass.BranchExists("gpass"))
+	s.True(gpass.BranchExists("web/a.gpg"))
+	s.True(gpass.TagExists("b.gpg"))
+	s.ElementsMatch([]string{"master", "test"}, gpass.ListBranches())
+	s.Equal([]string{"web/a.gpg"}, gpass.ListAccounts())
+
+	_, err = gpass.root.Reference(trackingRef("origin", "web/a.gpg"), false)
+	s.NoError(err)
+
+	// HEAD was on an account branch and is detached so commits in the work tree leave it alone
+	h, err := gpass.root.Storer.Reference(plumbing.HEAD)
+	require.NoError(s.T(), err)
+	s.Equal(plumbing.HashReference, h.Type())
+	s.Equal(head.Hash(), h.Hash())
+
+	// a HEAD on a regular branch is left alone
+	other := s.newTestRepository("gpass-test")
+	defer os.RemoveAll(other.Path)
+
+	err = other.root.Storer.SetReference(plumbing.NewHashReference("refs/heads/gpass", head.Hash()))
+	require.NoError(s.T(), err)
+
+	res, err = other.Migrate()
+	require.NoError(s.T(), err)
+	s.Empty(res.Detached)
+
+	h, err = other.root.Storer.Reference(plumbing.HEAD)
+	require.NoError(s.T(), err)
+	s.Equal(plumbing.ReferenceName("refs/heads/master"), h.Target())
+}
+
+func (s *GitSuite) TestWriteFile() {
+	gpass := s.newTestRepository("gpass-test")
+	defer os.RemoveAll(gpass.Path)
+
+	err := gpass.CreateOrphanBranch(s.user, "web/a.gpg")
+	require.NoError(s.T(), err)
+	s.False(gpass.FileExists("web/a.gpg", "empty"))
+
+	err = gpass.WriteFile(s.user, "web/a.gpg", "web/a.gpg", []byte("a"), "add")
+	require.NoError(s.T(), err)
+	err = gpass.WriteFile(s.user, "web/a.gpg", "web/b", []byte("b"), "add")
+	require.NoError(s.T(), err)
+
+	f, err := gpass.ReadFile("web/a.gpg", "web/a.gpg")
+	require.NoError(s.T(), err)
+	s.Equal("a", string(f))
+
+	// the work tree and HEAD are left untouched
+	_, err = os.Stat(filepath.Join(gpass.Path, "web"))
+	s.True(os.IsNotExist(err))
+
+	head, err := gpass.root.Head()
+	require.NoError(s.T(), err)
+	s.Equal("refs/heads/master", string(head.Name()))
+
+	err = gpass.MoveFile(s.user, "web/a.gpg", "web/a.gpg", "a.gpg", "move")
+	require.NoError(s.T(), err)
+	s.False(gpass.FileExists("web/a.gpg", "web/a.gpg"))
+	s.True(gpass.FileExists("web/a.gpg", "a.gpg"))
+
+	err = gpass.RemoveFile(s.user, "web/a.gpg", "web/b", "remove")
+	require.NoError(s.T(), err)
+	s.False(gpass.FileExists("web/a.gpg", "web"))
+
+	h, err := gpass.History("web/a.gpg")
+	require.NoError(s.T(), err)
+	s.Len(h, 5)
+}
+
+func (s *GitSuite) TestSignedCommit() {
+	gpass := s.newTestRepository("gpass-test")
+	defer os.RemoveAll(gpass.Path)
+
+	e, err := openpgp.NewEntity(s.user.Name, "", s.user.Email, &packet.Config{RSABits: 1024})
+	require.NoError(s.T(), err)
+
+	var pub bytes.Buffer
+	a, err := armor.Encode(&pub, openpgp.PublicKeyType, nil)
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), e.Serialize(a))
+	require.NoError(s.T(), a.Close())
+
+	u := s.newUser()
+	u.SignKey = e
+
+	err = gpass.WriteFile(u, "a.gpg", "a.gpg", []byte("a"), "add")
+	require.NoError(s.T(), err)
+
+	ref, err := gpass.root.Reference(branchRef("a.gpg"), false)
+	require.NoError(s.T(), err)
+
+	c, err := gpass.root.CommitObject(ref.Hash())
+	require.NoError(s.T(), err)
+	s.NotEmpty(c.PGPSignature)
+
+	signer, err := c.Verify(pub.String())
+	require.NoError(s.T(), err)
+	s.Equal(e.PrimaryKey.KeyId, signer.PrimaryKey.KeyId)
+
+	signer, err = gpass.VerifyBranch("a.gpg", pub.Bytes())
+	require.NoError(s.T(), err)
+	s.Equal(e.PrimaryKey.KeyId, signer.PrimaryKey.KeyId)
+
+	_, err = gpass.VerifyBranch("a.gpg", nil)
+	s.Error(err)
+
+	// a signature by a key outside the keyring is not trusted
+	other, err := openpgp.NewEntity("Jane Doe", "", "jane@doe.org", &packet.Config{RSABits: 1024})
+	require.NoError(s.T(), err)
+
+	var otherPub bytes.Buffer
+	a, err = armor.Encode(&otherPub, openpgp.PublicKeyType, nil)
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), other.Serialize(a))
+	require.NoError(s.T(), a.Close())
+
+	_, err = gpass.VerifyRevision(ref.Hash().String(), otherPub.Bytes())
+	s.Error(err)
+
+	// commits without a key stay unsigned
+	err = gpass.WriteFile(s.user, "a.gpg", "a.gpg", []byte("b"), "edit")
+	require.NoError(s.T(), err)
+
+	ref, err = gpass.root.Reference(branchRef("a.gpg"), false)
+	require.NoError(s.T(), err)
+
+	c, err = gpass.root.CommitObject(ref.Hash())
+	require.NoError(s.T(), err)
+	s.Empty(c.PGPSignature)
+
+	_, err = gpass.VerifyBranch("a.gpg", pub.Bytes())
+	s.Error(err)
+}
+
+func (s *GitSuite) TestFastForwardVerify() {
+	dir, err := ioutil.TempDir("", "gpass-test")
+	require.NoError(s.T(), err)
+	defer os.RemoveAll(dir)
+
+	_, err = git.PlainInit(filepath.Join(dir, "remote.git"), true)
+	require.NoError(s.T(), err)
+	url := "file://" + filepath.Join(dir, "remote.git")
+
+	e, err := openpgp.NewEntity(s.user.Name, "", s.user.Email, &packet.Config{RSABits: 1024})
+	require.NoError(s.T(), err)
+
+	var pub bytes.Buffer
+	a, err := armor.Encode(&pub, openpgp.PublicKeyType, nil)
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), e.Serialize(a))
+	require.NoError(s.T(), a.Close())
+
+	u := s.newUser()
+	u.SignKey = e
+
+	local := &Repository{Path: filepath.Join(dir, "local")}
+	require.NoError(s.T(), local.Create())
+	require.NoError(s.T(), local.CreateOrphanBranch(u, "gpass"))
+	require.NoError(s.T(), local.WriteFile(u, "gpass", SignersFile, pub.Bytes(), "add signers"))
+	require.NoError(s.T(), local.WriteFile(u, "a.gpg", "a.gpg", []byte("a"), "add"))
+	require.NoError(s.T(), local.AddRemote("origin", url))
+	require.NoError(s.T(), local.Fetch("origin"))
+	_, err = local.Push("origin")
+	require.NoError(s.T(), err)
+
+	// a new clone trusts the signers of the remote on first use
+	clone := &Repository{Path: filepath.Join(dir, "clone")}
+	require.NoError(s.T(), clone.Create())
+	require.NoError(s.T(), clone.AddRemote("origin", url))
+	require.NoError(s.T(), clone.Fetch("origin"))
+
+	res, err := clone.FastForward("origin", false)
+	require.NoError(s.T(), err)
+	s.ElementsMatch([]string{"gpass", "a.gpg"}, res.Updated)
+	s.Empty(res.Untrusted)
+
+	// a signer added on the remote is trusted along with the changes they signed
+	other, err := openpgp.NewEntity("Jane Doe", "", "jane@doe.org", &packet.Config{RSABits: 1024})
+	require.NoError(s.T(), err)
+
+	var both bytes.Buffer
+	a, err = armor.Encode(&both, openpgp.PublicKeyType, nil)
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), e.Serialize(a))
+	require.NoError(s.T(), other.Serialize(a))
+	require.NoError(s.T(), a.Close())
+
+	jane := &User{Name: "Jane Doe", Email: "jane@doe.org", SignKey: other}
+	require.NoError(s.T(), local.WriteFile(u, "gpass", SignersFile, both.Bytes(), "add signers"))
+	require.NoError(s.T(), local.WriteFile(jane, "b.gpg", "b.gpg", []byte("b"), "add"))
+	_, err = local.Push("origin")
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), clone.Fetch("origin"))
+
+	res, err = clone.FastForward("origin", false)
+	require.NoError(s.T(), err)
+	s.ElementsMatch([]string{"gpass", "b.gpg"}, res.Updated)
+
+	gpassTip, err := clone.root.Reference(metaRef, false)
+	require.NoError(s.T(), err)
+	accountTip, err := clone.root.Reference(branchRef("a.gpg"), false)
+	require.NoError(s.T(), err)
+
+	// unsigned changes, such as adding a signer, are refused without updating any branch
+	require.NoError(s.T(), local.WriteFile(s.user, "gpass", SignersFile, []byte("forged"), "add signers"))
+	require.NoError(s.T(), local.WriteFile(s.user, "a.gpg", "a.gpg", []byte("b"), "edit"))
+	_, err = local.Push("origin")
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), clone.Fetch("origin"))
+
+	_, err = clone.FastForward("origin", false)
+	require.Error(s.T(), err)
+	untrusted, ok := err.(*UntrustedError)
+	require.True(s.T(), ok)
+	s.Equal("gpass", untrusted.Branch)
+
+	ref, err := clone.root.Reference(metaRef, false)
+	require.NoError(s.T(), err)
+	s.Equal(gpassTip.Hash(), ref.Hash())
+	ref, err = clone.root.Reference(branchRef("a.gpg"), false)
+	require.NoError(s.T(), err)
+	s.Equal(accountTip.Hash(), ref.Hash())
+
+	// insecure accepts them and reports every untrusted branch
+	res, err = clone.FastForward("origin", true)
 	require.NoError(s.T(), err)
+	s.ElementsMatch([]string{"gpass", "a.gpg"}, res.Updated)
+	require.Len(s.T(), res.Untrusted, 2)
+	s.Equal("gpass", res.Untrusted[0].Branch)
+	s.Equal("a.gpg", res.Untrusted[1].Branch)
 }
@@ -0,0 +1,324 @@
+package git
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+// JournalFile is the file on the gpass branch recording removed and moved account branches
+const JournalFile = ".journal"
+
+// JournalEntry records the removal or move of an account branch
+type JournalEntry struct {
+	// Op is either "rm" or "mv"
+	Op string
+	// Hash is the commit the operation applies to, the removal tag for rm and the moved branch for mv
+	Hash   string
+	Branch string
+	// To is the new branch of a move
+	To string
+}
+
+// ReplayResult lists the journal entries applied by a replay
+type ReplayResult struct {
+	Applied []*JournalEntry
+	// Conflicts are the entries not applied because the branch changed after it was removed or moved
+	Conflicts []*JournalEntry
+}
+
+func (e *JournalEntry) String() string {
+	if e.Op == "mv" {
+		return fmt.Sprintf("%s\t%s\t%s\t%s", e.Op, e.Hash, e.Branch, e.To)
+	}
+
+	return fmt.Sprintf("%s\t%s\t%s", e.Op, e.Hash, e.Branch)
+}
+
+// Journal returns the entries of the journal on the gpass branch in the order they were recorded
+func (r *Repository) Journal() ([]*JournalEntry, error) {
+	if !r.FileExists("gpass", JournalFile) {
+		return nil, nil
+	}
+
+	b, err := r.ReadFile("gpass", JournalFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*JournalEntry
+
+	for _, l := range strings.Split(string(b), "\n") {
+		if l == "" {
+			continue
+		}
+
+		f := strings.Split(l, "\t")
+		switch {
+		case f[0] == "rm" && len(f) == 3:
+			entries = append(entries, &JournalEntry{Op: f[0], Hash: f[1], Branch: f[2]})
+		case f[0] == "mv" && len(f) == 4:
+			entries = append(entries, &JournalEntry{Op: f[0], Hash: f[1], Branch: f[2], To: f[3]})
+		default:
+			return nil, fmt.Errorf("Invalid journal entry: %s", l)
+		}
+	}
+
+	return entries, nil
+}
+
+// RecordRemove records the removal of a branch that has been tagged
+func (r *Repository) RecordRemove(u *User, branch string) error {
+	ref, err := r.root.Reference(removedRef(branch), false)
+	if err != nil {
+		return err
+	}
+
+	e := &JournalEntry{Op: "rm", Hash: ref.Hash().String(), Branch: branch}
+
+	return r.record(u, e, fmt.Sprintf("Journal: remove %s", branch))
+}
+
+// RecordMove records the move of a branch to a new branch
+func (r *Repository) RecordMove(u *User, branch string, to string) error {
+	ref, err := r.root.Reference(branchRef(to), false)
+	if err != nil {
+		return err
+	}
+
+	e := &JournalEntry{Op: "mv", Hash: ref.Hash().String(), Branch: branch, To: to}
+
+	return r.record(u, e, fmt.Sprintf("Journal: move %s to %s", branch, to))
+}
+
+func (r *Repository) record(u *User, e *JournalEntry, msg string) error {
+	var b []byte
+
+	if r.FileExists("gpass", JournalFile) {
+		var err error
+		if b, err = r.ReadFile("gpass", JournalFile); err != nil {
+			return err
+		}
+	}
+
+	b = append(b, []byte(e.String()+"\n")...)
+
+	return r.WriteFile(u, "gpass", JournalFile, b, msg)
+}
+
+// Replay applies the journal to the local branches. A branch is removed when its tip is part of the
+// history the entry was recorded at, branches that have been recreated since are left untouched and
+// branches that changed concurrently are kept and returned as conflicts.
+func (r *Repository) Replay() (*ReplayResult, error) {
+	entries, err := r.Journal()
+	if err != nil {
+		return nil, err
+	}
+
+	res := new(ReplayResult)
+
+	for _, e := range entries {
+		h := plumbing.NewHash(e.Hash)
+
+		if e.Op == "rm" && !r.TagExists(e.Branch) {
+			if _, err := r.root.CommitObject(h); err == nil {
+				t := plumbing.NewHashReference(removedRef(e.Branch), h)
+				if err := r.root.Storer.SetReference(t); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		if e.Op == "mv" && !r.BranchExists(e.To) {
+			if _, err := r.root.CommitObject(h); err == nil {
+				b := plumbing.NewHashReference(branchRef(e.To), h)
+				if err := r.root.Storer.SetReference(b); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		name := branchRef(e.Branch)
+		ref, err := r.root.Reference(name, false)
+		if err != nil {
+			continue
+		}
+
+		state, err := r.compare(ref.Hash(), h)
+		if err != nil {
+			continue
+		}
+
+		switch state {
+		case equal, behind:
+			if err := r.root.Storer.RemoveReference(name); err != nil {
+				return nil, err
+			}
+			res.Applied = append(res.Applied, e)
+		case diverged:
+			if r.related(ref.Hash(), h) {
+				res.Conflicts = append(res.Conflicts, e)
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// removed returns true if the journal removes or moves a branch at the given commit
+func (r *Repository) removed(entries []*JournalEntry, branch string, h plumbing.Hash) bool {
+	for _, e := range entries {
+		if e.Branch != branch {
+			continue
+		}
+
+		if state, err := r.compare(h, plumbing.NewHash(e.Hash)); err == nil && (state == equal || state == behind) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// related returns true if two commits share history
+func (r *Repository) related(a plumbing.Hash, b plumbing.Hash) bool {
+	ac, err := r.root.CommitObject(a)
+	if err != nil {
+		return false
+	}
+
+	bc, err := r.root.CommitObject(b)
+	if err != nil {
+		return false
+	}
+
+	bases, err := ac.MergeBase(bc)
+
+	return err == nil && len(bases) > 0
+}
+
+// MergeBranch merges a branch that diverged from its remote tracking branch file by file. Files changed
+// on one side take that side, files changed on both sides are merged line by line if they are listed in
+// union and are returned as conflicts otherwise, in which case nothing is committed.
+func (r *Repository) MergeBranch(u *User, remote string, branch string, msg string, union ...string) ([]string, error) {
+	d, err := r.Divergence(remote, branch)
+	if err != nil {
+		return nil, err
+	}
+
+	base := map[string]plumbing.Hash{}
+	if d.Base != "" {
+		if base, err = r.treeFiles(plumbing.NewHash(d.Base)); err != nil {
+			return nil, err
+		}
+	}
+
+	local, err := r.treeFiles(plumbing.NewHash(d.Local))
+	if err != nil {
+		return nil, err
+	}
+
+	theirs, err := r.treeFiles(plumbing.NewHash(d.Remote))
+	if err != nil {
+		return nil, err
+	}
+
+	isUnion := func(f string) bool {
+		for _, n := range union {
+			if n == f {
+				return true
+			}
+		}
+		return false
+	}
+
+	files := map[string]bool{}
+	for _, m := range []map[string]plumbing.Hash{base, local, theirs} {
+		for f := range m {
+			files[f] = true
+		}
+	}
+
+	merged := map[string][]byte{}
+	var conflicts []string
+
+	for f := range files {
+		b, l, t := base[f], local[f], theirs[f]
+
+		switch {
+		case t == l || t == b:
+			continue
+		case l == b:
+			if t.IsZero() {
+				merged[f] = nil
+				continue
+			}
+			if merged[f], err = r.readFile(plumbing.NewHash(d.Remote), f); err != nil {
+				return nil, err
+			}
+		case isUnion(f):
+			lb, _ := r.readFile(plumbing.NewHash(d.Local), f)
+			tb, _ := r.readFile(plumbing.NewHash(d.Remote), f)
+			merged[f] = unionLines(lb, tb)
+		default:
+			conflicts = append(conflicts, f)
+		}
+	}
+
+	if len(conflicts) > 0 {
+		sort.Strings(conflicts)
+		return conflicts, nil
+	}
+
+	parents := []plumbing.Hash{plumbing.NewHash(d.Local), plumbing.NewHash(d.Remote)}
+	if err := r.commitTree(u, branch, parents, merged, msg); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// treeFiles returns the blob hashes of all files in the tree of a commit
+func (r *Repository) treeFiles(h plumbing.Hash) (map[string]plumbing.Hash, error) {
+	c, err := r.root.CommitObject(h)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := c.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	files := map[string]plumbing.Hash{}
+
+	err = t.Files().ForEach(func(f *object.File) error {
+		files[f.Name] = f.Hash
+		return nil
+	})
+
+	return files, err
+}
+
+// unionLines returns the lines of a followed by the lines of b that are not in a
+func unionLines(a []byte, b []byte) []byte {
+	seen := map[string]bool{}
+	var out []string
+
+	for _, l := range append(strings.Split(string(a), "\n"), strings.Split(string(b), "\n")...) {
+		if l == "" || seen[l] {
+			continue
+		}
+		seen[l] = true
+		out = append(out, l)
+	}
+
+	if len(out) == 0 {
+		return []byte{}
+	}
+
+	return []byte(strings.Join(out, "\n") + "\n")
+}
@@ -0,0 +1,185 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+// gpass keeps its references out of refs/heads and refs/tags so a store can share a repository with
+// regular branches: the gpass branch is refs/gpass/meta, account branches live under refs/gpass/accounts/,
+// the tombstones of removed accounts under refs/gpass/removed/ and remote tracking branches and tombstones
+// under refs/gpass/remotes/<remote>/
+const (
+	metaRef        = "refs/gpass/meta"
+	accountsPrefix = "refs/gpass/accounts/"
+	removedPrefix  = "refs/gpass/removed/"
+	remotesPrefix  = "refs/gpass/remotes/"
+)
+
+// branchRef returns the reference of a branch, the gpass branch and account branches (ending in .gpg)
+// have their own namespace and everything else is a regular branch
+func branchRef(s string) plumbing.ReferenceName {
+	switch {
+	case s == "gpass":
+		return metaRef
+	case strings.HasSuffix(s, ".gpg"):
+		return plumbing.ReferenceName(accountsPrefix + s)
+	}
+
+	return plumbing.ReferenceName("refs/heads/" + s)
+}
+
+// removedRef returns the reference of the tombstone of a removed account branch
+func removedRef(s string) plumbing.ReferenceName {
+	return plumbing.ReferenceName(removedPrefix + s)
+}
+
+// trackingRef returns the remote tracking reference of the gpass branch or an account branch
+func trackingRef(remote string, s string) plumbing.ReferenceName {
+	return plumbing.ReferenceName(remotesPrefix + remote + "/" + strings.TrimPrefix(branchRef(s).String(), "refs/gpass/"))
+}
+
+// trackedBranch returns the branch of a remote tracking reference, false if it is not one
+func trackedBranch(remote string, n plumbing.ReferenceName) (string, bool) {
+	prefix := remotesPrefix + remote + "/"
+	if !strings.HasPrefix(n.String(), prefix) {
+		return "", false
+	}
+
+	return gpassBranch(plumbing.ReferenceName("refs/gpass/" + strings.TrimPrefix(n.String(), prefix)))
+}
+
+// trackingRemovedRef returns the remote tracking reference of the tombstone of a removed account branch
+func trackingRemovedRef(remote string, s string) plumbing.ReferenceName {
+	return plumbing.ReferenceName(remotesPrefix + remote + "/removed/" + s)
+}
+
+// trackedRemoved returns the branch of a remote tracking tombstone, false if it is not one
+func trackedRemoved(remote string, n plumbing.ReferenceName) (string, bool) {
+	prefix := remotesPrefix + remote + "/removed/"
+	if !strings.HasPrefix(n.String(), prefix) {
+		return "", false
+	}
+
+	return strings.TrimPrefix(n.String(), prefix), true
+}
+
+// gpassBranch returns the name of the gpass branch or an account branch, false for any other reference
+func gpassBranch(n plumbing.ReferenceName) (string, bool) {
+	switch {
+	case n == metaRef:
+		return "gpass", true
+	case strings.HasPrefix(n.String(), accountsPrefix):
+		return strings.TrimPrefix(n.String(), accountsPrefix), true
+	}
+
+	return "", false
+}
+
+// ListAccounts returns the names of all account branches
+func (r *Repository) ListAccounts() []string {
+	var b []string
+
+	refs, _ := r.root.References()
+	refs.ForEach(func(ref *plumbing.Reference) error {
+		if strings.HasPrefix(ref.Name().String(), accountsPrefix) {
+			b = append(b, strings.TrimPrefix(ref.Name().String(), accountsPrefix))
+		}
+		return nil
+	})
+
+	return b
+}
+
+// Legacy returns true if the store still keeps its references in refs/heads and refs/tags
+func (r *Repository) Legacy() bool {
+	_, err := r.root.Reference("refs/heads/gpass", false)
+	return err == nil
+}
+
+// MigrateResult describes the references moved by Migrate
+type MigrateResult struct {
+	// Moved is the number of moved references
+	Moved int
+	// Detached is the hash HEAD was detached at because it was attached to a moved reference, empty otherwise
+	Detached string
+}
+
+// Migrate moves the gpass branch, account branches, removal tags and remote tracking branches of a
+// store created by an older version of gpass to their own namespace. The references on remotes are
+// left untouched. A HEAD attached to one of the moved branches is detached at the same commit, so
+// commits in the work tree never move a gpass branch and the work tree still matches HEAD.
+func (r *Repository) Migrate() (*MigrateResult, error) {
+	refs, err := r.root.References()
+	if err != nil {
+		return nil, err
+	}
+
+	remotes, err := r.root.Remotes()
+	if err != nil {
+		return nil, err
+	}
+
+	moves := map[plumbing.ReferenceName]plumbing.ReferenceName{}
+
+	refs.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() != plumbing.HashReference {
+			return nil
+		}
+
+		n := ref.Name()
+		switch {
+		case n == "refs/heads/gpass":
+			moves[n] = metaRef
+		case n.IsBranch() && strings.HasSuffix(n.Short(), ".gpg"):
+			moves[n] = branchRef(n.Short())
+		case n.IsTag() && strings.HasSuffix(n.Short(), ".gpg"):
+			moves[n] = removedRef(n.Short())
+		case n.IsRemote():
+			for _, rem := range remotes {
+				remote := rem.Config().Name
+				b := strings.TrimPrefix(n.String(), fmt.Sprintf("refs/remotes/%s/", remote))
+				if b != n.String() && (b == "gpass" || strings.HasSuffix(b, ".gpg")) {
+					moves[n] = trackingRef(remote, b)
+				}
+			}
+		}
+		return nil
+	})
+
+	res := &MigrateResult{Moved: len(moves)}
+
+	head, err := r.root.Storer.Reference(plumbing.HEAD)
+	if err == nil && head.Type() == plumbing.SymbolicReference {
+		if _, ok := moves[head.Target()]; ok {
+			ref, err := r.root.Reference(head.Target(), false)
+			if err != nil {
+				return nil, err
+			}
+
+			if err := r.root.Storer.SetReference(plumbing.NewHashReference(plumbing.HEAD, ref.Hash())); err != nil {
+				return nil, fmt.Errorf("Unable to detach HEAD: %s", err)
+			}
+			res.Detached = ref.Hash().String()
+		}
+	}
+
+	for old, n := range moves {
+		ref, err := r.root.Reference(old, false)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := r.root.Storer.SetReference(plumbing.NewHashReference(n, ref.Hash())); err != nil {
+			return nil, fmt.Errorf("Unable to create %s: %s", n, err)
+		}
+
+		if err := r.root.Storer.RemoveReference(old); err != nil {
+			return nil, fmt.Errorf("Unable to remove %s: %s", old, err)
+		}
+	}
+
+	return res, nil
+}
@@ -0,0 +1,487 @@
+package git
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	git "gopkg.in/src-d/go-git.v4"
+	gitconfig "gopkg.in/src-d/go-git.v4/config"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+)
+
+// SyncResult lists the branches affected by synchronizing with a remote
+type SyncResult struct {
+	// Updated are the branches that were created or fast-forwarded
+	Updated []string
+	// Diverged are the branches where both sides have commits the other side does not have
+	Diverged []string
+	// RemovedDiverged are the removed branches whose tombstones diverged from the remote tombstones
+	RemovedDiverged []string
+	// Untrusted are the branches that were accepted under insecure without a trusted signature
+	Untrusted []*UntrustedError
+}
+
+// Divergence holds the commits of a branch that diverged from its remote tracking branch
+type Divergence struct {
+	Branch string
+	// Local is the hash of the local branch
+	Local string
+	// Remote is the hash of the remote tracking branch
+	Remote string
+	// Base is the hash of the common ancestor, empty if the histories are unrelated
+	Base string
+}
+
+// AddRemote adds a remote to the repository or returns an error
+func (r *Repository) AddRemote(name string, url string) error {
+	_, err := r.root.CreateRemote(&gitconfig.RemoteConfig{
+		Name: name,
+		URLs: []string{url},
+	})
+	if err != nil {
+		return fmt.Errorf("Unable to add the remote %s: %s", name, err)
+	}
+
+	return nil
+}
+
+// Fetch fetches the gpass branch, all account branches and removal tombstones from a remote, they are
+// stored as remote tracking references under refs/gpass/remotes/<remote>/ and applied by FastForward
+func (r *Repository) Fetch(remote string) error {
+	specs := []gitconfig.RefSpec{
+		gitconfig.RefSpec(fmt.Sprintf("+%s:%s", metaRef, trackingRef(remote, "gpass"))),
+		gitconfig.RefSpec(fmt.Sprintf("+%s*:%s%s/accounts/*", accountsPrefix, remotesPrefix, remote)),
+		gitconfig.RefSpec(fmt.Sprintf("+%s*:%s%s/removed/*", removedPrefix, remotesPrefix, remote)),
+	}
+
+	err := r.root.Fetch(&git.FetchOptions{
+		RemoteName: remote,
+		RefSpecs:   specs,
+		Tags:       git.NoTags,
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate && err != transport.ErrEmptyRemoteRepository {
+		return fmt.Errorf("Unable to fetch from %s: %s", remote, err)
+	}
+
+	return r.prune(remote)
+}
+
+// prune removes the remote tracking branches and tombstones that no longer exist on a remote
+func (r *Repository) prune(remote string) error {
+	rem, err := r.root.Remote(remote)
+	if err != nil {
+		return err
+	}
+
+	advertised, err := rem.List(&git.ListOptions{})
+	if err != nil && err != transport.ErrEmptyRemoteRepository {
+		return fmt.Errorf("Unable to list the references of %s: %s", remote, err)
+	}
+
+	exists := map[plumbing.ReferenceName]bool{}
+	for _, ref := range advertised {
+		exists[ref.Name()] = true
+	}
+
+	refs, err := r.root.References()
+	if err != nil {
+		return err
+	}
+
+	var stale []plumbing.ReferenceName
+	refs.ForEach(func(ref *plumbing.Reference) error {
+		if branch, ok := trackedBranch(remote, ref.Name()); ok && !exists[branchRef(branch)] {
+			stale = append(stale, ref.Name())
+		}
+		if branch, ok := trackedRemoved(remote, ref.Name()); ok && !exists[removedRef(branch)] {
+			stale = append(stale, ref.Name())
+		}
+		return nil
+	})
+
+	for _, n := range stale {
+		if err := r.root.Storer.RemoveReference(n); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// FastForward updates the local branches and tombstones to the ones fetched from a remote, branches that
+// only exist on the remote are created unless the journal removed them and diverged branches and
+// tombstones are left untouched. Nothing is updated when the incoming changes of a branch are not signed
+// by a trusted signer, unless insecure is set.
+func (r *Repository) FastForward(remote string, insecure bool) (*SyncResult, error) {
+	res := new(SyncResult)
+
+	untrusted, err := r.verifyIncoming(remote)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(untrusted) > 0 && !insecure {
+		return nil, untrusted[0]
+	}
+	res.Untrusted = untrusted
+
+	entries, err := r.Journal()
+	if err != nil {
+		return nil, err
+	}
+
+	refs, err := r.root.References()
+	if err != nil {
+		return nil, err
+	}
+
+	var remoteRefs []*plumbing.Reference
+	refs.ForEach(func(ref *plumbing.Reference) error {
+		if _, ok := trackedBranch(remote, ref.Name()); ok && ref.Type() == plumbing.HashReference {
+			remoteRefs = append(remoteRefs, ref)
+		}
+		return nil
+	})
+
+	for _, ref := range remoteRefs {
+		branch, _ := trackedBranch(remote, ref.Name())
+		name := branchRef(branch)
+
+		local, err := r.root.Reference(name, false)
+		if err != nil {
+			// a branch removed locally that has not been deleted from the remote yet
+			if r.removed(entries, branch, ref.Hash()) {
+				continue
+			}
+			if err := r.root.Storer.SetReference(plumbing.NewHashReference(name, ref.Hash())); err != nil {
+				return nil, err
+			}
+			res.Updated = append(res.Updated, branch)
+			continue
+		}
+
+		state, err := r.compare(local.Hash(), ref.Hash())
+		if err != nil {
+			return nil, err
+		}
+
+		switch state {
+		case behind:
+			if err := r.root.Storer.SetReference(plumbing.NewHashReference(name, ref.Hash())); err != nil {
+				return nil, err
+			}
+			res.Updated = append(res.Updated, branch)
+		case diverged:
+			res.Diverged = append(res.Diverged, branch)
+		}
+	}
+
+	removed, err := r.remoteRemoved(remote)
+	if err != nil {
+		return nil, err
+	}
+
+	for branch, ref := range removed {
+		state, err := r.compareRemoved(branch, ref)
+		if err != nil {
+			return nil, err
+		}
+
+		switch state {
+		case behind:
+			if err := r.root.Storer.SetReference(plumbing.NewHashReference(removedRef(branch), ref.Hash())); err != nil {
+				return nil, err
+			}
+		case diverged:
+			res.RemovedDiverged = append(res.RemovedDiverged, branch)
+		}
+	}
+	sort.Strings(res.RemovedDiverged)
+
+	return res, nil
+}
+
+// remoteRemoved returns the remote tracking tombstones fetched from a remote by branch name
+func (r *Repository) remoteRemoved(remote string) (map[string]*plumbing.Reference, error) {
+	refs, err := r.root.References()
+	if err != nil {
+		return nil, err
+	}
+
+	removed := map[string]*plumbing.Reference{}
+	refs.ForEach(func(ref *plumbing.Reference) error {
+		if branch, ok := trackedRemoved(remote, ref.Name()); ok && ref.Type() == plumbing.HashReference {
+			removed[branch] = ref
+		}
+		return nil
+	})
+
+	return removed, nil
+}
+
+// compareRemoved returns how the local tombstone of a branch relates to a remote tombstone, a missing
+// local tombstone is behind
+func (r *Repository) compareRemoved(branch string, remote *plumbing.Reference) (syncState, error) {
+	local, err := r.root.Reference(removedRef(branch), false)
+	if err != nil {
+		return behind, nil
+	}
+
+	return r.compare(local.Hash(), remote.Hash())
+}
+
+// Incoming returns the hashes of the remote tracking branches fetched from a remote that hold commits
+// the local branches do not have, by branch name. Branches FastForward would skip are left out.
+func (r *Repository) Incoming(remote string) (map[string]string, error) {
+	in := map[string]string{}
+
+	entries, err := r.Journal()
+	if err != nil {
+		return nil, err
+	}
+
+	refs, err := r.root.References()
+	if err != nil {
+		return nil, err
+	}
+
+	var remoteRefs []*plumbing.Reference
+	refs.ForEach(func(ref *plumbing.Reference) error {
+		if _, ok := trackedBranch(remote, ref.Name()); ok && ref.Type() == plumbing.HashReference {
+			remoteRefs = append(remoteRefs, ref)
+		}
+		return nil
+	})
+
+	for _, ref := range remoteRefs {
+		branch, _ := trackedBranch(remote, ref.Name())
+
+		local, err := r.root.Reference(branchRef(branch), false)
+		if err != nil {
+			if !r.removed(entries, branch, ref.Hash()) {
+				in[branch] = ref.Hash().String()
+			}
+			continue
+		}
+
+		state, err := r.compare(local.Hash(), ref.Hash())
+		if err != nil {
+			return nil, err
+		}
+
+		if state == behind || state == diverged {
+			in[branch] = ref.Hash().String()
+		}
+	}
+
+	return in, nil
+}
+
+// Push pushes the gpass branch, all account branches and removal tombstones to a remote. Branches and
+// tombstones that are behind or diverged from the last fetched state of the remote are skipped and
+// returned, tombstones as removed/<branch>, branches removed or moved according to the journal are
+// deleted from the remote.
+func (r *Repository) Push(remote string) ([]string, error) {
+	var specs []gitconfig.RefSpec
+	var pushed []*plumbing.Reference
+	var pushedRemoved []*plumbing.Reference
+	var deleted []plumbing.ReferenceName
+	var skipped []string
+
+	entries, err := r.Journal()
+	if err != nil {
+		return nil, err
+	}
+
+	refs, err := r.root.References()
+	if err != nil {
+		return nil, err
+	}
+
+	var local []*plumbing.Reference
+	var removed []*plumbing.Reference
+	refs.ForEach(func(ref *plumbing.Reference) error {
+		if ref.Type() != plumbing.HashReference {
+			return nil
+		}
+
+		n := ref.Name()
+		if branch, ok := trackedBranch(remote, n); ok {
+			if !r.BranchExists(branch) && r.removed(entries, branch, ref.Hash()) {
+				specs = append(specs, gitconfig.RefSpec(fmt.Sprintf(":%s", branchRef(branch))))
+				deleted = append(deleted, n)
+			}
+		}
+		if _, ok := gpassBranch(n); ok {
+			local = append(local, ref)
+		}
+		if strings.HasPrefix(n.String(), removedPrefix) {
+			removed = append(removed, ref)
+		}
+		return nil
+	})
+
+	for _, ref := range local {
+		branch, _ := gpassBranch(ref.Name())
+		tracking := trackingRef(remote, branch)
+
+		if rr, err := r.root.Reference(tracking, false); err == nil {
+			state, err := r.compare(ref.Hash(), rr.Hash())
+			if err != nil {
+				return nil, err
+			}
+
+			if state == equal {
+				continue
+			}
+
+			if state != ahead {
+				skipped = append(skipped, branch)
+				continue
+			}
+		}
+
+		specs = append(specs, gitconfig.RefSpec(fmt.Sprintf("%s:%s", ref.Name(), ref.Name())))
+		pushed = append(pushed, ref)
+	}
+
+	for _, ref := range removed {
+		branch := strings.TrimPrefix(ref.Name().String(), removedPrefix)
+
+		if rr, err := r.root.Reference(trackingRemovedRef(remote, branch), false); err == nil {
+			state, err := r.compare(ref.Hash(), rr.Hash())
+			if err != nil {
+				return nil, err
+			}
+
+			if state == equal {
+				continue
+			}
+
+			if state != ahead {
+				skipped = append(skipped, "removed/"+branch)
+				continue
+			}
+		}
+
+		specs = append(specs, gitconfig.RefSpec(fmt.Sprintf("%s:%s", ref.Name(), ref.Name())))
+		pushedRemoved = append(pushedRemoved, ref)
+	}
+
+	if len(specs) == 0 {
+		return skipped, nil
+	}
+
+	err = r.root.Push(&git.PushOptions{
+		RemoteName: remote,
+		RefSpecs:   specs,
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return nil, fmt.Errorf("Unable to push to %s: %s", remote, err)
+	}
+
+	for _, n := range deleted {
+		if err := r.root.Storer.RemoveReference(n); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, ref := range pushed {
+		branch, _ := gpassBranch(ref.Name())
+		tracking := trackingRef(remote, branch)
+		if err := r.root.Storer.SetReference(plumbing.NewHashReference(tracking, ref.Hash())); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, ref := range pushedRemoved {
+		tracking := trackingRemovedRef(remote, strings.TrimPrefix(ref.Name().String(), removedPrefix))
+		if err := r.root.Storer.SetReference(plumbing.NewHashReference(tracking, ref.Hash())); err != nil {
+			return nil, err
+		}
+	}
+
+	return skipped, nil
+}
+
+// Divergence returns the local, remote and common commits of a branch fetched from a remote
+func (r *Repository) Divergence(remote string, branch string) (*Divergence, error) {
+	local, err := r.root.Reference(branchRef(branch), false)
+	if err != nil {
+		return nil, err
+	}
+
+	rr, err := r.root.Reference(trackingRef(remote, branch), false)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Divergence{
+		Branch: branch,
+		Local:  local.Hash().String(),
+		Remote: rr.Hash().String(),
+	}
+
+	l, err := r.root.CommitObject(local.Hash())
+	if err != nil {
+		return nil, err
+	}
+
+	rc, err := r.root.CommitObject(rr.Hash())
+	if err != nil {
+		return nil, err
+	}
+
+	bases, err := l.MergeBase(rc)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to find the common history of %s: %s", branch, err)
+	}
+
+	if len(bases) > 0 {
+		d.Base = bases[0].Hash.String()
+	}
+
+	return d, nil
+}
+
+type syncState int
+
+const (
+	equal syncState = iota
+	ahead
+	behind
+	diverged
+)
+
+// compare returns how the local commit relates to the remote commit
+func (r *Repository) compare(local plumbing.Hash, remote plumbing.Hash) (syncState, error) {
+	if local == remote {
+		return equal, nil
+	}
+
+	l, err := r.root.CommitObject(local)
+	if err != nil {
+		return diverged, err
+	}
+
+	rc, err := r.root.CommitObject(remote)
+	if err != nil {
+		return diverged, err
+	}
+
+	if ok, err := rc.IsAncestor(l); err != nil {
+		return diverged, err
+	} else if ok {
+		return ahead, nil
+	}
+
+	if ok, err := l.IsAncestor(rc); err != nil {
+		return diverged, err
+	} else if ok {
+		return behind, nil
+	}
+
+	return diverged, nil
+}
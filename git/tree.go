@@ -0,0 +1,247 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/openpgp"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/filemode"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+)
+
+// WriteFile commits the content of a file to the tip of a branch in the object store, without
+// touching the work tree. The branch is created if it does not exist yet.
+func (r *Repository) WriteFile(u *User, branch string, filename string, content []byte, msg string) error {
+	return r.commitFiles(u, branch, map[string][]byte{filename: content}, msg)
+}
+
+// RemoveFile commits the removal of a file from the tip of a branch or returns an error
+func (r *Repository) RemoveFile(u *User, branch string, filename string, msg string) error {
+	return r.commitFiles(u, branch, map[string][]byte{filename: nil}, msg)
+}
+
+// MoveFile commits the rename of a file on the tip of a branch or returns an error
+func (r *Repository) MoveFile(u *User, branch string, from string, to string, msg string) error {
+	b, err := r.ReadFile(branch, from)
+	if err != nil {
+		return err
+	}
+
+	return r.commitFiles(u, branch, map[string][]byte{from: nil, to: b}, msg)
+}
+
+// CommitMerge commits the content of a file to a branch with the given parents or returns an error,
+// the other files are taken from the first parent
+func (r *Repository) CommitMerge(u *User, branch string, filename string, content []byte, msg string, parents ...string) error {
+	var h []plumbing.Hash
+	for _, p := range parents {
+		h = append(h, plumbing.NewHash(p))
+	}
+
+	return r.commitTree(u, branch, h, map[string][]byte{filename: content}, msg)
+}
+
+// commitFiles commits changes to the tip of a branch, a nil content removes the file
+func (r *Repository) commitFiles(u *User, branch string, files map[string][]byte, msg string) error {
+	var parents []plumbing.Hash
+
+	if ref, err := r.root.Reference(branchRef(branch), false); err == nil {
+		parents = append(parents, ref.Hash())
+	}
+
+	return r.commitTree(u, branch, parents, files, msg)
+}
+
+// commitTree applies changes to the tree of the first parent, commits it and moves the branch to the commit
+func (r *Repository) commitTree(u *User, branch string, parents []plumbing.Hash, files map[string][]byte, msg string) error {
+	var base *object.Tree
+
+	if len(parents) > 0 {
+		c, err := r.root.CommitObject(parents[0])
+		if err != nil {
+			return err
+		}
+
+		if base, err = c.Tree(); err != nil {
+			return err
+		}
+	}
+
+	tree, err := r.buildTree(base, files)
+	if err != nil {
+		return fmt.Errorf("Unable to build the tree: %s", err)
+	}
+
+	sig := object.Signature{
+		Name:  u.Name,
+		Email: u.Email,
+		When:  time.Now(),
+	}
+
+	c := &object.Commit{
+		Author:       sig,
+		Committer:    sig,
+		Message:      msg,
+		TreeHash:     tree,
+		ParentHashes: parents,
+	}
+
+	if u.SignKey != nil {
+		sig, err := signCommit(c, u.SignKey)
+		if err != nil {
+			return fmt.Errorf("Unable to sign the commit: %s", err)
+		}
+		c.PGPSignature = sig
+	}
+
+	obj := r.root.Storer.NewEncodedObject()
+	if err := c.Encode(obj); err != nil {
+		return fmt.Errorf("Unable to commit: %s", err)
+	}
+
+	h, err := r.root.Storer.SetEncodedObject(obj)
+	if err != nil {
+		return fmt.Errorf("Unable to commit: %s", err)
+	}
+
+	if err := r.root.Storer.SetReference(plumbing.NewHashReference(branchRef(branch), h)); err != nil {
+		return fmt.Errorf("Unable to update %s: %s", branch, err)
+	}
+
+	return nil
+}
+
+// signCommit returns the armored detached signature of a commit that is not signed yet
+func signCommit(c *object.Commit, key *openpgp.Entity) (string, error) {
+	obj := &plumbing.MemoryObject{}
+	if err := c.Encode(obj); err != nil {
+		return "", err
+	}
+
+	r, err := obj.Reader()
+	if err != nil {
+		return "", err
+	}
+
+	var b bytes.Buffer
+	if err := openpgp.ArmoredDetachSign(&b, key, r, nil); err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
+
+// buildTree writes a tree with the changes applied to base, which may be nil, and returns its hash
+func (r *Repository) buildTree(base *object.Tree, files map[string][]byte) (plumbing.Hash, error) {
+	entries := map[string]object.TreeEntry{}
+
+	if base != nil {
+		for _, e := range base.Entries {
+			entries[e.Name] = e
+		}
+	}
+
+	dirs := map[string]map[string][]byte{}
+
+	for f, content := range files {
+		f = strings.Trim(path.Clean(f), "/")
+
+		if i := strings.Index(f, "/"); i >= 0 {
+			d := f[:i]
+			if dirs[d] == nil {
+				dirs[d] = map[string][]byte{}
+			}
+			dirs[d][f[i+1:]] = content
+			continue
+		}
+
+		if content == nil {
+			delete(entries, f)
+			continue
+		}
+
+		h, err := r.writeBlob(content)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+
+		entries[f] = object.TreeEntry{Name: f, Mode: filemode.Regular, Hash: h}
+	}
+
+	for d, changes := range dirs {
+		var sub *object.Tree
+
+		if e, ok := entries[d]; ok && e.Mode == filemode.Dir {
+			t, err := r.root.TreeObject(e.Hash)
+			if err != nil {
+				return plumbing.ZeroHash, err
+			}
+			sub = t
+		}
+
+		h, err := r.buildTree(sub, changes)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+
+		// git does not track empty folders
+		if h == emptyTree {
+			delete(entries, d)
+			continue
+		}
+
+		entries[d] = object.TreeEntry{Name: d, Mode: filemode.Dir, Hash: h}
+	}
+
+	t := &object.Tree{}
+	for _, e := range entries {
+		t.Entries = append(t.Entries, e)
+	}
+
+	// git orders tree entries by name, comparing folders as if they end in a slash
+	key := func(e object.TreeEntry) string {
+		if e.Mode == filemode.Dir {
+			return e.Name + "/"
+		}
+		return e.Name
+	}
+	sort.Slice(t.Entries, func(i, j int) bool {
+		return key(t.Entries[i]) < key(t.Entries[j])
+	})
+
+	obj := r.root.Storer.NewEncodedObject()
+	if err := t.Encode(obj); err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	return r.root.Storer.SetEncodedObject(obj)
+}
+
+// emptyTree is the hash of a tree without entries
+var emptyTree = plumbing.NewHash("4b825dc642cb6eb9a060e54bf8d69288fbee4904")
+
+func (r *Repository) writeBlob(content []byte) (plumbing.Hash, error) {
+	obj := r.root.Storer.NewEncodedObject()
+	obj.SetType(plumbing.BlobObject)
+
+	w, err := obj.Writer()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	if _, err := w.Write(content); err != nil {
+		w.Close()
+		return plumbing.ZeroHash, err
+	}
+
+	if err := w.Close(); err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	return r.root.Storer.SetEncodedObject(obj)
+}
@@ -0,0 +1,133 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"golang.org/x/crypto/openpgp"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+// SignersFile is the armored keyring on the gpass branch holding the public keys trusted to sign commits
+const SignersFile = ".signers"
+
+// UntrustedError is returned when the incoming changes of a branch are not signed by a trusted signer
+type UntrustedError struct {
+	Branch string
+	Err    error
+}
+
+func (e *UntrustedError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Branch, e.Err)
+}
+
+// VerifyBranch returns the signer of the tip of a branch if it is signed by a key in the armored keyring
+func (r *Repository) VerifyBranch(branch string, keyring []byte) (*openpgp.Entity, error) {
+	ref, err := r.root.Reference(branchRef(branch), false)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to find a branch named %s", branch)
+	}
+
+	return r.VerifyRevision(ref.Hash().String(), keyring)
+}
+
+// VerifyRevision returns the signer of a commit if it is signed by a key in the armored keyring
+func (r *Repository) VerifyRevision(hash string, keyring []byte) (*openpgp.Entity, error) {
+	c, err := r.root.CommitObject(plumbing.NewHash(hash))
+	if err != nil {
+		return nil, fmt.Errorf("Unable to find the commit %s: %s", hash, err)
+	}
+
+	if c.PGPSignature == "" {
+		return nil, fmt.Errorf("Commit %s is not signed", hash[:7])
+	}
+
+	if len(bytes.TrimSpace(keyring)) == 0 {
+		return nil, fmt.Errorf("Commit %s can not be verified without trusted signers", hash[:7])
+	}
+
+	e, err := c.Verify(string(keyring))
+	if err != nil {
+		return nil, fmt.Errorf("Commit %s is not signed by a trusted signer: %s", hash[:7], err)
+	}
+
+	return e, nil
+}
+
+// verifyIncoming verifies the incoming changes fetched from a remote and returns the branches and
+// tombstones, as removed/<branch>, that are not signed by a trusted signer. The incoming gpass branch is
+// verified against the signers of the local gpass branch, a store without one trusts the signers of the
+// fetched gpass branch on first use. Everything else is verified against the signers of the incoming
+// gpass branch once it is trusted, so signers added on the remote are trusted along with their changes.
+func (r *Repository) verifyIncoming(remote string) ([]*UntrustedError, error) {
+	in, err := r.Incoming(remote)
+	if err != nil {
+		return nil, err
+	}
+
+	var keyring []byte
+
+	from := plumbing.ReferenceName(metaRef)
+	if !r.BranchExists("gpass") {
+		from = trackingRef(remote, "gpass")
+	}
+
+	if ref, err := r.root.Reference(from, false); err == nil {
+		if b, err := r.readFile(ref.Hash(), SignersFile); err == nil {
+			keyring = b
+		}
+	}
+
+	var untrusted []*UntrustedError
+
+	if h, ok := in["gpass"]; ok {
+		if _, err := r.VerifyRevision(h, keyring); err != nil {
+			untrusted = append(untrusted, &UntrustedError{Branch: "gpass", Err: err})
+		} else {
+			keyring, _ = r.readFile(plumbing.NewHash(h), SignersFile)
+		}
+	}
+
+	var branches []string
+	for b := range in {
+		if b != "gpass" {
+			branches = append(branches, b)
+		}
+	}
+	sort.Strings(branches)
+
+	for _, b := range branches {
+		if _, err := r.VerifyRevision(in[b], keyring); err != nil {
+			untrusted = append(untrusted, &UntrustedError{Branch: b, Err: err})
+		}
+	}
+
+	removed, err := r.remoteRemoved(remote)
+	if err != nil {
+		return nil, err
+	}
+
+	branches = nil
+	for b := range removed {
+		branches = append(branches, b)
+	}
+	sort.Strings(branches)
+
+	for _, b := range branches {
+		state, err := r.compareRemoved(b, removed[b])
+		if err != nil {
+			return nil, err
+		}
+
+		if state != behind {
+			continue
+		}
+
+		if _, err := r.VerifyRevision(removed[b].Hash().String(), keyring); err != nil {
+			untrusted = append(untrusted, &UntrustedError{Branch: "removed/" + b, Err: err})
+		}
+	}
+
+	return untrusted, nil
+}
@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"os/exec"
 	"path"
 	"path/filepath"
 	"strings"
@@ -34,6 +35,25 @@ func TouchFile(filename string) error {
 	return nil
 }
 
+// WritePrivateFile writes to a new file only readable by the user, fails on existing files
+func WritePrivateFile(filename string, b []byte) error {
+	if err := os.MkdirAll(path.Dir(filename), os.FileMode(0700)); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("Unable to create the file: %s", err)
+	}
+
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return fmt.Errorf("Unable to write to file: %s", err)
+	}
+
+	return f.Close()
+}
+
 // DeletePath removes everything in a path (incl. dirs)
 func DeletePath(path string) error {
 	err := os.RemoveAll(path)
@@ -151,6 +171,28 @@ func ConfirmShellPrompt(s string) bool {
 	}
 }
 
+// ChoiceShellPrompt loads a prompt until one of the choices is entered and returns it
+func ChoiceShellPrompt(s string, choices []string) string {
+	reader := bufio.NewReader(os.Stdin)
+
+	for {
+		fmt.Printf("%s [%s]: ", s, strings.Join(choices, "/"))
+
+		response, err := reader.ReadString('\n')
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		response = strings.ToLower(strings.TrimSpace(response))
+
+		for _, c := range choices {
+			if response == c {
+				return c
+			}
+		}
+	}
+}
+
 // PassShellPrompt loads a shell prompt for entering and confirming a passphrase
 func PassShellPrompt(prompts []string) ([]byte, error) {
 
@@ -178,3 +220,88 @@ func PassShellPrompt(prompts []string) ([]byte, error) {
 
 	return p, nil
 }
+
+// MultilineShellPrompt reads lines from stdin until EOF
+func MultilineShellPrompt(s string) ([]byte, error) {
+	fmt.Println(s)
+
+	b, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read from stdin: %s", err)
+	}
+
+	return b, nil
+}
+
+// EditorShellPrompt opens content in $EDITOR from a private temporary file and returns the edited content
+func EditorShellPrompt(content []byte) ([]byte, error) {
+	dir := ""
+	if fi, err := os.Stat("/dev/shm"); err == nil && fi.IsDir() {
+		dir = "/dev/shm"
+	}
+
+	f, err := ioutil.TempFile(dir, "gpass-")
+	if err != nil {
+		return nil, fmt.Errorf("Unable to create a temporary file: %s", err)
+	}
+	defer ShredFile(f.Name())
+
+	if err := f.Chmod(os.FileMode(0600)); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("Unable to change permissions on file to 0600: %s", err)
+	}
+
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("Unable to write to file: %s", err)
+	}
+
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+
+	editor := strings.Fields(os.Getenv("EDITOR"))
+	if len(editor) == 0 {
+		editor = []string{"vi"}
+	}
+
+	cmd := exec.Command(editor[0], append(editor[1:], f.Name())...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("Unable to run the editor %s: %s", editor[0], err)
+	}
+
+	return LoadFile(f.Name())
+}
+
+// ShredFile overwrites a file with zeros before removing it
+func ShredFile(filename string) error {
+	fi, err := os.Stat(filename)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(filename, os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(make([]byte, fi.Size())); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Remove(filename)
+}